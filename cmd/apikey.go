@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/zatrasz75/Service/pkg/auth"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"github.com/zatrasz75/Service/pkg/storage/postgres"
+	"strconv"
+	"strings"
+)
+
+// runAPIKey выполняет подкоманду apikey: create NAME [ROLE] [TENANT], list [TENANT], revoke ID [TENANT].
+// Позволяет создать первый ключ доступа, когда API уже требует аутентификации,
+// поэтому по умолчанию ключ получает роль admin. Без TENANT используется арендатор по умолчанию.
+func runAPIKey(db *postgres.Store, args []string) error {
+	if len(args) == 0 {
+		return errors.New("использование: apikey create NAME [ROLE] [TENANT] | list [TENANT] | revoke ID [TENANT]")
+	}
+	ctx, err := tenantArg(args, map[string]int{"create": 3, "list": 1, "revoke": 2}[args[0]])
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "create":
+		if len(args) < 2 || strings.TrimSpace(args[1]) == "" {
+			return errors.New("использование: apikey create NAME [ROLE] [TENANT]")
+		}
+		role := auth.RoleAdmin
+		if len(args) > 2 {
+			role = args[2]
+		}
+		if !auth.ValidRole(role) {
+			return fmt.Errorf("неизвестная роль: %s", role)
+		}
+		key, prefix, hash, err := auth.GenerateAPIKey()
+		if err != nil {
+			return err
+		}
+		k, err := db.CreateAPIKey(ctx, strings.TrimSpace(args[1]), role, prefix, hash)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d\t%s\t%s\t%s\t%s\n", k.ID, k.Name, k.Role, k.TenantID, key)
+		return nil
+	case "list":
+		keys, err := db.ListAPIKeys(ctx)
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			state := "active"
+			if k.RevokedAt != nil {
+				state = "revoked " + k.RevokedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%d\t%s\t%s\t%s…\t%s\n", k.ID, k.Name, k.Role, k.Prefix, state)
+		}
+		return nil
+	case "revoke":
+		if len(args) < 2 {
+			return errors.New("использование: apikey revoke ID [TENANT]")
+		}
+		id, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || id <= 0 {
+			return fmt.Errorf("некорректный идентификатор: %s", args[1])
+		}
+		return db.RevokeAPIKey(ctx, id)
+	default:
+		return fmt.Errorf("неизвестная команда apikey: %s", args[0])
+	}
+}
+
+// tenantArg возвращает контекст арендатора из аргумента с индексом i, если он передан.
+func tenantArg(args []string, i int) (context.Context, error) {
+	ctx := context.Background()
+	if i == 0 || len(args) <= i {
+		return ctx, nil
+	}
+	if !storage.ValidTenant(args[i]) {
+		return nil, fmt.Errorf("некорректный идентификатор арендатора: %s", args[i])
+	}
+	return storage.WithTenant(ctx, args[i]), nil
+}
@@ -1,11 +1,23 @@
 package main
 
 import (
+	"context"
 	"github.com/joho/godotenv"
 	"github.com/zatrasz75/Service/configs"
 	"github.com/zatrasz75/Service/pkg/api"
+	"github.com/zatrasz75/Service/pkg/auth"
+	"github.com/zatrasz75/Service/pkg/feed"
+	"github.com/zatrasz75/Service/pkg/grpcapi"
+	"github.com/zatrasz75/Service/pkg/health"
+	"github.com/zatrasz75/Service/pkg/jobs"
 	"github.com/zatrasz75/Service/pkg/logger"
+	"github.com/zatrasz75/Service/pkg/metrics"
 	"github.com/zatrasz75/Service/pkg/service"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"github.com/zatrasz75/Service/pkg/storage/postgres"
+	"github.com/zatrasz75/Service/pkg/tracing"
+	"github.com/zatrasz75/Service/pkg/webhooks"
+	"os"
 )
 
 // init вызывается перед main() и загружает значения из файла .env в систему
@@ -18,12 +30,107 @@ func init() {
 func main() {
 	cfg := configs.New()
 
+	// Подкоманда openapi check сверяет спецификацию с маршрутами API и завершает работу
+	if len(os.Args) > 2 && os.Args[1] == "openapi" && os.Args[2] == "check" {
+		httpServer, err := api.New(cfg, nil, nil, nil, nil, nil)
+		if err == nil {
+			err = httpServer.CheckSpec()
+		}
+		if err != nil {
+			logger.Fatal("Проверка спецификации OpenAPI не пройдена", err)
+		}
+		logger.Info("Спецификация OpenAPI соответствует API")
+		return
+	}
+
+	// Единый пул соединений с базой данных для API и потребителя Kafka
+	db, err := postgres.New(cfg.DataBase)
+	if err != nil {
+		logger.Fatal("нет соединения с PostgresSQL", err)
+	}
+	defer db.Close()
+	metrics.Register(metrics.NewPoolCollector(db.Stat))
+
+	// Подкоманда migrate управляет схемой базы данных и завершает работу
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err = runMigrate(db, os.Args[2:])
+		if err != nil {
+			logger.Fatal("Ошибка выполнения миграций", err)
+		}
+		return
+	}
+
+	err = db.MigrateUp(context.Background())
+	if err != nil {
+		logger.Fatal("не удалось применить миграции", err)
+	}
+
+	// Подкоманда apikey управляет ключами доступа и завершает работу
+	if len(os.Args) > 1 && os.Args[1] == "apikey" {
+		err = runAPIKey(db, os.Args[2:])
+		if err != nil {
+			logger.Fatal("Ошибка выполнения команды apikey", err)
+		}
+		return
+	}
+
+	// Трассировка запросов HTTP, сообщений Kafka, обогащения и запросов к базе данных
+	shutdownTracing, err := tracing.Init(cfg.Tracing)
+	if err != nil {
+		logger.Fatal("Не удалось настроить трассировку", err)
+	}
+
+	// Проверка ключей доступа и токенов JWT для REST и gRPC
+	authn, err := auth.New(cfg.Auth, db)
+	if err != nil {
+		logger.Fatal("Не удалось настроить аутентификацию", err)
+	}
+
+	// Фоновые задачи обрабатывают данные всех арендаторов
+	sysCtx := storage.WithTenant(context.Background(), storage.AllTenants)
+
+	// Задача окончательного удаления записей после срока хранения
+	if cfg.DataBase.SoftDeleteRetention > 0 && cfg.DataBase.PurgeInterval > 0 {
+		go jobs.StartPurge(sysCtx, db, cfg.DataBase.PurgeInterval, cfg.DataBase.SoftDeleteRetention)
+	}
+
+	// Клиент Kafka, общий для API и потребителя
+	kfk, err := service.New(cfg.Kafka.Brokers, cfg.Kafka.Topic, cfg.Kafka.TopicErr, cfg.Kafka.GroupID)
+	if err != nil {
+		logger.Fatal("Не удалось создать клиента Kafka", err)
+	}
+	metrics.Register(metrics.NewReaderCollector(kfk.Reader, kfk.RejectionReader))
+
+	// Публикация событий изменения записей из outbox
+	if cfg.Kafka.TopicEvents != "" {
+		go kfk.RelayOutbox(sysCtx, db, cfg.Kafka.TopicEvents, cfg.Kafka.OutboxInterval, cfg.Kafka.OutboxBatchSize)
+	}
+
+	// Доставка событий подписчикам webhooks
+	go webhooks.New(cfg.Webhooks, db).Run(sysCtx)
+
+	// Поток изменений записей для SSE, WebSocket и gRPC
+	hub := feed.NewHub(db, cfg.Server.StreamPollInterval)
+	go hub.Run(sysCtx)
+
+	// Проверки зависимостей для /readyz. Недоступность сервисов обогащения
+	// не мешает принимать запросы, записи сохраняются без обогащения.
+	checker := health.New(cfg.Server.HealthTimeout,
+		health.Check{Name: "database", Critical: true, Check: db.Ping},
+		health.Check{Name: "migrations", Critical: true, Check: db.CheckMigrations},
+		health.Check{Name: "kafka", Critical: true, Check: kfk.Ping},
+		health.Check{Name: "enrichment", Check: service.PingEnrichment},
+	)
+
 	// Каналы для управления остановкой приложений
 	kafkaDoneCh := make(chan struct{})
 	serverDoneCh := make(chan struct{})
 
 	// Экземпляр API
-	httpServer := api.New()
+	httpServer, err := api.New(cfg, db, kfk, hub, authn, checker)
+	if err != nil {
+		logger.Fatal("Некорректные ограничения запросов", err)
+	}
 
 	// Запуск сервера в горутине
 	go func() {
@@ -34,9 +141,19 @@ func main() {
 		close(serverDoneCh)
 	}()
 
+	// Сервер gRPC запускается на отдельном порту, если он задан
+	var grpcServer *grpcapi.API
+	if cfg.GRPC.AddrPort != "" {
+		grpcServer = grpcapi.New(cfg, db, hub, authn)
+		err = grpcServer.Run()
+		if err != nil {
+			logger.Fatal("Ошибка при запуске сервера gRPC:", err)
+		}
+	}
+
 	// Запуск сервиса Kafka в горутине
 	go func() {
-		err := service.Start(cfg.Kafka.Brokers, cfg.Kafka.Topic, cfg.Kafka.TopicErr, cfg.Kafka.GroupID, cfg.DataBase.ConnStr)
+		err := service.Start(kfk, db)
 		if err != nil {
 			logger.Fatal("Не удалось запустить сервис Kafka", err)
 		}
@@ -50,5 +167,15 @@ func main() {
 	}
 
 	api.GraceShutdown(httpServer)
+	if grpcServer != nil {
+		grpcServer.Stop()
+	}
+
+	// Отправка накопленных трасс
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTime)
+	defer cancel()
+	if err = shutdownTracing(ctx); err != nil {
+		logger.Error("Не удалось отправить трассы", err)
+	}
 
 }
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/zatrasz75/Service/pkg/storage/postgres"
+	"strconv"
+)
+
+// runMigrate выполняет подкоманду migrate: up, down [N], status, force V.
+func runMigrate(db *postgres.Store, args []string) error {
+	if len(args) == 0 {
+		return errors.New("использование: migrate up | down [N] | status | force V")
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		return db.MigrateUp(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("некорректное количество шагов: %s", args[1])
+			}
+			steps = n
+		}
+		return db.MigrateDown(ctx, steps)
+	case "status":
+		statuses, err := db.MigrationsStatus(ctx)
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			state := "pending"
+			if st.Applied {
+				state = "applied " + st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if st.Dirty {
+				state = "dirty"
+			}
+			fmt.Printf("%04d_%s\t%s\n", st.Version, st.Name, state)
+		}
+		return nil
+	case "force":
+		if len(args) < 2 {
+			return errors.New("использование: migrate force V")
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil || version < 0 {
+			return fmt.Errorf("некорректная версия: %s", args[1])
+		}
+		return db.ForceVersion(ctx, version)
+	default:
+		return fmt.Errorf("неизвестная команда migrate: %s", args[0])
+	}
+}
@@ -4,13 +4,18 @@ import (
 	"fmt"
 	"github.com/zatrasz75/Service/pkg/logger"
 	"os"
+	"strconv"
 	"time"
 )
 
 type Config struct {
 	Server   Server
+	GRPC     GRPC
 	DataBase DataBase
 	Kafka    Kafka
+	Webhooks Webhooks
+	Auth     Auth
+	Tracing  Tracing
 }
 
 type Server struct {
@@ -20,6 +25,74 @@ type Server struct {
 	WriteTimeout time.Duration
 	IdleTimeout  time.Duration
 	ShutdownTime time.Duration
+
+	// Проверки состояния (/healthz, /readyz).
+	ShutdownDelay time.Duration // время, в течение которого /readyz сообщает о завершении работы до остановки сервера
+	HealthTimeout time.Duration // время выполнения проверок зависимостей, по умолчанию 2s
+
+	RequireIfMatch bool // обязательный заголовок If-Match для PUT и PATCH
+	BulkMaxItems   int  // максимальное количество элементов в пакетном запросе
+
+	StatsCacheTTL time.Duration // время жизни кеша статистики, 0 отключает кеширование
+
+	// Поток изменений записей (SSE, WebSocket, gRPC WatchPeople).
+	StreamPollInterval time.Duration // период опроса истории, если уведомление от базы данных не получено
+	StreamHeartbeat    time.Duration // период отправки пустых сообщений для поддержания соединения
+
+	// Ограничения запросов. Нулевые значения отключают ограничение. Маршруты задаются
+	// методом и путём без префикса версии, как в спецификации.
+	MaxBodySize       int64         // максимальный размер тела запроса в байтах
+	BodyLimits        string        // размер тела для отдельных маршрутов вида "METHOD /path=bytes;..."
+	RateLimit         int           // количество запросов клиента за RateLimitWindow
+	RateLimitWindow   time.Duration // период, за который восстанавливается RateLimit запросов
+	RateLimitBurst    int           // наибольшее количество запросов клиента подряд, по умолчанию RateLimit
+	IPRateLimit       int           // количество запросов с IP-адреса за RateLimitWindow до аутентификации
+	ConcurrencyLimits string        // одновременные запросы к маршрутам вида "METHOD /path=n;..."
+
+	// Маршруты без версии. Нулевые даты не передаются в заголовках.
+	LegacyDeprecation time.Time // дата объявления маршрутов устаревшими (заголовок Deprecation)
+	LegacySunset      time.Time // дата отключения маршрутов (заголовок Sunset)
+}
+
+// GRPC Настройки сервера gRPC. Пустой порт отключает сервер.
+type GRPC struct {
+	AddrPort string
+}
+
+// Webhooks Настройки доставки событий подписчикам. Нулевые значения означают значения по умолчанию.
+type Webhooks struct {
+	Timeout      time.Duration // время ожидания ответа подписчика
+	MaxAttempts  int           // количество попыток, после которого доставка считается неудавшейся
+	Backoff      time.Duration // задержка перед второй попыткой, далее удваивается
+	PollInterval time.Duration // период проверки очереди доставок
+	Concurrency  int           // количество одновременных запросов
+	AllowPrivate bool          // разрешить адреса подписчиков в локальных и внутренних сетях
+}
+
+// Auth Настройки аутентификации. Токены JWT проверяются, если задан JWKSURL или JWKSFile.
+type Auth struct {
+	Enabled     bool          // требовать ключ доступа или токен
+	JWKSURL     string        // адрес набора открытых ключей
+	JWKSFile    string        // локальный файл набора ключей, запасной источник при недоступности URL
+	JWKSRefresh time.Duration // период обновления набора ключей по URL
+	JWTIssuer   string        // ожидаемый claim iss, пустой не проверяется
+	JWTAudience string        // ожидаемый claim aud, пустой не проверяется
+
+	// Авторизация по ролям.
+	JWTRolesClaim    string // claim токена с ролями
+	JWTTenantClaim   string // claim токена с арендатором
+	RoutePermissions string // разрешения маршрутов вида "METHOD /path=permission;..."
+	HiddenFields     string // поля, скрытые от ролей, вида "role=field,field;..."
+}
+
+// Tracing Настройки трассировки OpenTelemetry. Пустой Exporter отключает трассировку.
+type Tracing struct {
+	Exporter     string  // otlp, stdout или file
+	OTLPEndpoint string  // адрес коллектора OTLP/gRPC, по умолчанию OTEL_EXPORTER_OTLP_ENDPOINT
+	OTLPInsecure bool    // подключение к коллектору без TLS
+	File         string  // файл для экспортёра file
+	SampleRatio  float64 // доля трассируемых запросов от 0 до 1, 0 — все запросы
+	ServiceName  string  // имя сервиса в трассах
 }
 
 type DataBase struct {
@@ -31,6 +104,22 @@ type DataBase struct {
 	Url      string // localhost
 	Name     string // Account
 	Port     string // 49153
+
+	// Настройки пула соединений. Нулевые значения означают значения по умолчанию pgxpool.
+	MaxConns          int32         // максимальное количество соединений в пуле
+	MinConns          int32         // минимальное количество открытых соединений
+	MaxConnLifetime   time.Duration // максимальное время жизни соединения
+	MaxConnIdleTime   time.Duration // максимальное время простоя соединения
+	HealthCheckPeriod time.Duration // период проверки состояния соединений
+
+	// RowLevelSecurity устанавливает арендатора запроса в app.tenant_id каждого соединения,
+	// чтобы политики строк Postgres дополнительно ограничивали запросы арендатором.
+	// Без него соединения получают значение '*' и политики не ограничивают запросы.
+	RowLevelSecurity bool
+
+	// Окончательное удаление записей после мягкого удаления. Нулевой срок хранения отключает задачу.
+	SoftDeleteRetention time.Duration // срок хранения удалённых записей
+	PurgeInterval       time.Duration // период запуска задачи очистки
 }
 
 type Kafka struct {
@@ -40,6 +129,11 @@ type Kafka struct {
 	Topic    string
 	TopicErr string
 	GroupID  string
+
+	// Публикация событий изменения записей из outbox. Пустой топик отключает публикацию.
+	TopicEvents     string
+	OutboxInterval  time.Duration // период проверки outbox
+	OutboxBatchSize int           // максимальное количество событий в одной публикации
 }
 
 func initDB() string {
@@ -72,64 +166,154 @@ func initBrokres() []string {
 	return brokers
 }
 
-func New() *Config {
-	readTimeoutStr := os.Getenv("READ_TIMEOUT")
-	var readTimeout time.Duration
-	if readTimeoutStr != "" {
-		var err error
-		readTimeout, err = time.ParseDuration(readTimeoutStr)
-		if err != nil {
-			logger.Error("ошибки парсинга времени", err)
-		}
+// parseDuration читает длительность из переменной окружения.
+// Пустое или некорректное значение даёт ноль.
+func parseDuration(key string) time.Duration {
+	str := os.Getenv(key)
+	if str == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(str)
+	if err != nil {
+		logger.Error("ошибки парсинга времени", err)
+		return 0
 	}
+	return d
+}
 
-	writeTimeoutStr := os.Getenv("WRITE_TIMEOUT")
-	var writeTimeout time.Duration
-	if writeTimeoutStr != "" {
-		var err error
-		writeTimeout, err = time.ParseDuration(writeTimeoutStr)
-		if err != nil {
-			logger.Error("ошибки парсинга времени", err)
-		}
+// parseInt читает целое число из переменной окружения.
+// Пустое или некорректное значение даёт ноль.
+func parseInt(key string) int {
+	str := os.Getenv(key)
+	if str == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(str)
+	if err != nil {
+		logger.Error("ошибки парсинга числа "+key, err)
+		return 0
 	}
+	return n
+}
 
-	idleTimeoutStr := os.Getenv("IDLE_TIMEOUT")
-	var idleTimeout time.Duration
-	if idleTimeoutStr != "" {
-		var err error
-		idleTimeout, err = time.ParseDuration(idleTimeoutStr)
-		if err != nil {
-			logger.Error("ошибки парсинга времени", err)
-		}
+// parseFloat читает дробное число из переменной окружения.
+// Пустое или некорректное значение даёт ноль.
+func parseFloat(key string) float64 {
+	str := os.Getenv(key)
+	if str == "" {
+		return 0
 	}
+	f, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		logger.Error("ошибки парсинга числа "+key, err)
+		return 0
+	}
+	return f
+}
 
-	shutdownTimeStr := os.Getenv("SHUTDOWN_TIMEOUT")
-	var shutdownTime time.Duration
-	if shutdownTimeStr != "" {
-		var err error
-		shutdownTime, err = time.ParseDuration(shutdownTimeStr)
-		if err != nil {
-			logger.Error("ошибки парсинга времени", err)
-		}
+// parseDate читает дату в формате RFC 3339 или YYYY-MM-DD из переменной окружения.
+// Пустое или некорректное значение даёт нулевое время.
+func parseDate(key string) time.Time {
+	str := os.Getenv(key)
+	if str == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, str)
+	if err == nil {
+		return t
 	}
+	t, err = time.Parse(time.DateOnly, str)
+	if err != nil {
+		logger.Error("ошибки парсинга даты "+key, err)
+		return time.Time{}
+	}
+	return t
+}
 
+func New() *Config {
 	return &Config{
 		Server: Server{
 			AddrHost:     os.Getenv("APP_HOST"),
 			AddrPort:     os.Getenv("APP_PORT"),
-			ReadTimeout:  readTimeout,
-			WriteTimeout: writeTimeout,
-			IdleTimeout:  idleTimeout,
-			ShutdownTime: shutdownTime,
+			ReadTimeout:  parseDuration("READ_TIMEOUT"),
+			WriteTimeout: parseDuration("WRITE_TIMEOUT"),
+			IdleTimeout:  parseDuration("IDLE_TIMEOUT"),
+			ShutdownTime: parseDuration("SHUTDOWN_TIMEOUT"),
+
+			ShutdownDelay: parseDuration("SHUTDOWN_DELAY"),
+			HealthTimeout: parseDuration("HEALTH_CHECK_TIMEOUT"),
+
+			RequireIfMatch: os.Getenv("REQUIRE_IF_MATCH") == "true",
+			BulkMaxItems:   parseInt("BULK_MAX_ITEMS"),
+			StatsCacheTTL:  parseDuration("STATS_CACHE_TTL"),
+
+			StreamPollInterval: parseDuration("STREAM_POLL_INTERVAL"),
+			StreamHeartbeat:    parseDuration("STREAM_HEARTBEAT"),
+
+			MaxBodySize:       int64(parseInt("MAX_BODY_SIZE")),
+			BodyLimits:        os.Getenv("ROUTE_BODY_LIMITS"),
+			RateLimit:         parseInt("RATE_LIMIT"),
+			RateLimitWindow:   parseDuration("RATE_LIMIT_WINDOW"),
+			RateLimitBurst:    parseInt("RATE_LIMIT_BURST"),
+			IPRateLimit:       parseInt("RATE_LIMIT_IP"),
+			ConcurrencyLimits: os.Getenv("ROUTE_CONCURRENCY_LIMITS"),
+
+			LegacyDeprecation: parseDate("LEGACY_DEPRECATION_DATE"),
+			LegacySunset:      parseDate("LEGACY_SUNSET_DATE"),
+		},
+		GRPC: GRPC{
+			AddrPort: os.Getenv("GRPC_PORT"),
 		},
 		DataBase: DataBase{
-			ConnStr: initDB(),
+			ConnStr:           initDB(),
+			MaxConns:          int32(parseInt("DB_MAX_CONNS")),
+			MinConns:          int32(parseInt("DB_MIN_CONNS")),
+			MaxConnLifetime:   parseDuration("DB_MAX_CONN_LIFETIME"),
+			MaxConnIdleTime:   parseDuration("DB_MAX_CONN_IDLE_TIME"),
+			HealthCheckPeriod: parseDuration("DB_HEALTH_CHECK_PERIOD"),
+			RowLevelSecurity:  os.Getenv("DB_ROW_LEVEL_SECURITY") == "true",
+
+			SoftDeleteRetention: parseDuration("SOFT_DELETE_RETENTION"),
+			PurgeInterval:       parseDuration("PURGE_INTERVAL"),
 		},
 		Kafka: Kafka{
 			Topic:    os.Getenv("KAFKA_TOPIC"),
 			TopicErr: os.Getenv("KAFKA_TOPIC_ERR"),
 			GroupID:  os.Getenv("KAFKA_GROUP_ID"),
 			Brokers:  initBrokres(),
+
+			TopicEvents:     os.Getenv("KAFKA_TOPIC_EVENTS"),
+			OutboxInterval:  parseDuration("OUTBOX_INTERVAL"),
+			OutboxBatchSize: parseInt("OUTBOX_BATCH_SIZE"),
+		},
+		Webhooks: Webhooks{
+			Timeout:      parseDuration("WEBHOOK_TIMEOUT"),
+			MaxAttempts:  parseInt("WEBHOOK_MAX_ATTEMPTS"),
+			Backoff:      parseDuration("WEBHOOK_BACKOFF"),
+			PollInterval: parseDuration("WEBHOOK_POLL_INTERVAL"),
+			Concurrency:  parseInt("WEBHOOK_CONCURRENCY"),
+			AllowPrivate: os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS") == "true",
+		},
+		Auth: Auth{
+			Enabled:     os.Getenv("AUTH_ENABLED") == "true",
+			JWKSURL:     os.Getenv("AUTH_JWKS_URL"),
+			JWKSFile:    os.Getenv("AUTH_JWKS_FILE"),
+			JWKSRefresh: parseDuration("AUTH_JWKS_REFRESH"),
+			JWTIssuer:   os.Getenv("AUTH_JWT_ISSUER"),
+			JWTAudience: os.Getenv("AUTH_JWT_AUDIENCE"),
+
+			JWTRolesClaim:    os.Getenv("AUTH_JWT_ROLES_CLAIM"),
+			JWTTenantClaim:   os.Getenv("AUTH_JWT_TENANT_CLAIM"),
+			RoutePermissions: os.Getenv("AUTH_ROUTE_PERMISSIONS"),
+			HiddenFields:     os.Getenv("AUTH_HIDDEN_FIELDS"),
+		},
+		Tracing: Tracing{
+			Exporter:     os.Getenv("TRACING_EXPORTER"),
+			OTLPEndpoint: os.Getenv("TRACING_OTLP_ENDPOINT"),
+			OTLPInsecure: os.Getenv("TRACING_OTLP_INSECURE") == "true",
+			File:         os.Getenv("TRACING_FILE"),
+			SampleRatio:  parseFloat("TRACING_SAMPLE_RATIO"),
+			ServiceName:  os.Getenv("TRACING_SERVICE_NAME"),
 		},
 	}
 }
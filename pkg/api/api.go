@@ -4,14 +4,18 @@ import (
 	"context"
 	"github.com/gorilla/mux"
 	"github.com/zatrasz75/Service/configs"
+	"github.com/zatrasz75/Service/pkg/auth"
+	"github.com/zatrasz75/Service/pkg/feed"
 	"github.com/zatrasz75/Service/pkg/handlers"
+	"github.com/zatrasz75/Service/pkg/health"
 	"github.com/zatrasz75/Service/pkg/logger"
+	"github.com/zatrasz75/Service/pkg/service"
 	"github.com/zatrasz75/Service/pkg/storage"
-	"github.com/zatrasz75/Service/pkg/storage/postgres"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 // API представляет собой приложение с набором обработчиков.
@@ -20,8 +24,11 @@ type API struct {
 	port   string      // Порт
 	host   string      // Хост
 	srv    *http.Server
+	cfg    configs.Server   // Настройки сервера
 	PG     storage.Database // база данных
 	server *handlers.Server
+	auth   *auth.Authenticator // nil отключает аутентификацию
+	limits *limits             // ограничения размера, частоты и количества одновременных запросов
 }
 
 // Router возвращает маршрутизатор запросов.
@@ -29,44 +36,57 @@ func (api *API) Router() *mux.Router {
 	return api.r
 }
 
-func New() *API {
-	// Конфигурация
-	cfg := configs.New()
-	PG, err := postgres.New(cfg.DataBase.ConnStr)
-	if err != nil {
-		logger.Fatal("нет соединения с PostgresSQL", err)
+// New создаёт API с переданной конфигурацией, общим подключением к базе данных, клиентом Kafka,
+// потоком изменений записей, проверкой учётных данных и проверками зависимостей.
+// Возвращает ошибку, если заданы некорректные ограничения запросов.
+func New(cfg *configs.Config, PG storage.Database, kfk *service.Client, hub *feed.Hub, authn *auth.Authenticator, checker *health.Checker) (*API, error) {
+	api := &API{
+		r:    mux.NewRouter(),
+		host: cfg.Server.AddrHost,
+		port: cfg.Server.AddrPort,
+		cfg:  cfg.Server,
+		PG:   PG,
+		auth: authn,
+		server: &handlers.Server{
+			PG:             PG,
+			RequireIfMatch: cfg.Server.RequireIfMatch,
+			BulkMaxItems:   cfg.Server.BulkMaxItems,
+			Kafka:          kfk,
+			StatsCacheTTL:  cfg.Server.StatsCacheTTL,
+
+			Feed:            hub,
+			StreamHeartbeat: cfg.Server.StreamHeartbeat,
+
+			WebhookAllowPrivate: cfg.Webhooks.AllowPrivate,
+			Health:              checker,
+		},
 	}
-	err = PG.CreateDataTable()
+	limits, err := newLimits(cfg.Server)
 	if err != nil {
-		logger.Fatal("не удалось создать таблицу", err)
+		return nil, err
 	}
+	api.limits = limits
 
-	api := &API{
-		r:      mux.NewRouter(),
-		host:   cfg.Server.AddrHost,
-		port:   cfg.Server.AddrPort,
-		PG:     PG,
-		server: &handlers.Server{PG: PG},
-	}
 	// Регистрируем обработчики API.
+	api.r.Use(api.trace, api.instrument)
 	api.endpoints()
+	api.docsEndpoints()
+	api.metricsEndpoints()
+	api.healthEndpoints()
 
-	return api
+	return api, nil
 }
 
 // Run Метод для запуска сервера
 func (api *API) Run() error {
-	// Конфигурация
-	cfg := configs.New()
-
 	api.srv = &http.Server{
 		Addr:         api.host + ":" + api.port,
 		Handler:      api.r,
-		ReadTimeout:  cfg.Server.ReadTimeout,
-		WriteTimeout: cfg.Server.WriteTimeout,
-		IdleTimeout:  cfg.Server.IdleTimeout,
+		ReadTimeout:  api.cfg.ReadTimeout,
+		WriteTimeout: api.cfg.WriteTimeout,
+		IdleTimeout:  api.cfg.IdleTimeout,
 	}
-	logger.Info("Запуск сервера на http://" + api.srv.Addr + "/data")
+	logger.Info("Запуск сервера на http://" + api.srv.Addr + currentPrefix + "/data")
 
 	go func() {
 		err := api.srv.ListenAndServe()
@@ -79,12 +99,16 @@ func (api *API) Run() error {
 	return nil
 }
 
-// Stop Метод для остановки сервера
+// Stop Метод для остановки сервера.
+// Сначала /readyz начинает сообщать о завершении работы, и в течение ShutdownDelay
+// сервер продолжает принимать запросы, пока балансировщик не исключит его.
 func (api *API) Stop() error {
-	// Конфигурация
-	cfg := configs.New()
+	if api.server.Health != nil {
+		api.server.Health.Shutdown()
+		time.Sleep(api.cfg.ShutdownDelay)
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTime)
+	ctx, cancel := context.WithTimeout(context.Background(), api.cfg.ShutdownTime)
 	defer cancel()
 	err := api.srv.Shutdown(ctx)
 	if err != nil {
@@ -119,10 +143,35 @@ func shutdownServer(httpServer *API) error {
 }
 
 // Регистрация обработчиков API.
+// Каждая версия монтируется под своим префиксом. Маршруты без версии
+// сохраняются как устаревший псевдоним текущей версии.
+// Все маршруты API, кроме документации, требуют аутентификации и разрешения роли,
+// ограничены по размеру тела, частоте и количеству одновременных запросов
+// и работают с данными арендатора запроса.
 func (api *API) endpoints() {
-	api.r.HandleFunc("/data", api.server.GetData).Methods(http.MethodGet)
-	api.r.HandleFunc("/data", api.server.AddData).Methods(http.MethodPost)
-	api.r.HandleFunc("/data/{id}", api.server.DeleteData).Methods(http.MethodDelete)
-	api.r.HandleFunc("/data/{id}", api.server.UpdateData).Methods(http.MethodPut)
-	api.r.HandleFunc("/data/{id}", api.server.PartialUpdateData).Methods(http.MethodPatch)
+	for _, v := range api.versions() {
+		sub := api.r.PathPrefix(v.prefix).Subrouter()
+		api.middlewares(sub, v.prefix)
+		v.routes(sub)
+	}
+
+	legacy := api.r.NewRoute().Subrouter()
+	legacy.Use(api.deprecated)
+	api.middlewares(legacy, "")
+	api.legacyRoutes(legacy)
+}
+
+// middlewares подключает к маршрутам версии с префиксом prefix общие обработчики запросов.
+// Частота запросов с IP-адреса ограничивается до аутентификации, а частота запросов клиента —
+// после неё, чтобы учитывать ключ клиента.
+func (api *API) middlewares(r *mux.Router, prefix string) {
+	r.Use(api.limitBody(prefix), api.ipRateLimit)
+	if api.auth != nil {
+		r.Use(api.auth.Middleware)
+	}
+	r.Use(api.rateLimit)
+	if api.auth != nil {
+		r.Use(api.authorize(prefix))
+	}
+	r.Use(api.limitConcurrency(prefix), api.tenant)
 }
@@ -0,0 +1,258 @@
+package api
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/zatrasz75/Service/configs"
+	"github.com/zatrasz75/Service/pkg/auth"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// limits Ограничения размера тела, частоты и количества одновременных запросов.
+type limits struct {
+	maxBody     int64            // размер тела по умолчанию, 0 — без ограничения
+	bodyLimits  map[string]int64 // размер тела для отдельных маршрутов
+	limiter     *rateLimiter     // nil отключает ограничение частоты
+	ipLimiter   *rateLimiter     // ограничение частоты до аутентификации, nil отключает его
+	concurrency map[string]chan struct{}
+}
+
+// newLimits создаёт ограничения запросов из настроек сервера.
+// Возвращает ошибку, если правила маршрутов заданы некорректно.
+func newLimits(cfg configs.Server) (*limits, error) {
+	bodyLimits, bodyErr := parseRouteLimits(cfg.BodyLimits)
+	concurrency, concurrencyErr := parseRouteLimits(cfg.ConcurrencyLimits)
+
+	l := &limits{maxBody: cfg.MaxBodySize, bodyLimits: bodyLimits, concurrency: make(map[string]chan struct{})}
+	for route, n := range concurrency {
+		if n > 0 {
+			l.concurrency[route] = make(chan struct{}, n)
+		}
+	}
+	if cfg.RateLimit > 0 && cfg.RateLimitWindow > 0 {
+		burst := cfg.RateLimitBurst
+		if burst <= 0 {
+			burst = cfg.RateLimit
+		}
+		l.limiter = newRateLimiter(cfg.RateLimit, cfg.RateLimitWindow, burst)
+	}
+	if cfg.IPRateLimit > 0 && cfg.RateLimitWindow > 0 {
+		l.ipLimiter = newRateLimiter(cfg.IPRateLimit, cfg.RateLimitWindow, cfg.IPRateLimit)
+	}
+
+	if bodyErr != nil {
+		return nil, fmt.Errorf("ROUTE_BODY_LIMITS: %w", bodyErr)
+	}
+	if concurrencyErr != nil {
+		return nil, fmt.Errorf("ROUTE_CONCURRENCY_LIMITS: %w", concurrencyErr)
+	}
+	return l, nil
+}
+
+// parseRouteLimits разбирает правила вида "METHOD /path=n;...".
+// Возвращает корректные правила и ошибку первого некорректного.
+func parseRouteLimits(s string) (map[string]int64, error) {
+	result := make(map[string]int64)
+	var firstErr error
+	for _, rule := range strings.Split(s, ";") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		route, value, ok := strings.Cut(rule, "=")
+		method, path, okRoute := strings.Cut(strings.TrimSpace(route), " ")
+		path = strings.TrimSpace(path)
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if !ok || !okRoute || !strings.HasPrefix(path, "/") || err != nil || n < 0 {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("некорректное правило %q", rule)
+			}
+			continue
+		}
+		result[strings.ToUpper(method)+" "+path] = n
+	}
+	return result, firstErr
+}
+
+// limitBody отклоняет с ответом 413 запросы, тело которых превышает ограничение маршрута,
+// и ограничивает чтение тела запросов без Content-Length.
+func (api *API) limitBody(prefix string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limit := api.limits.maxBody
+			if n, ok := api.limits.bodyLimits[routeKey(r, prefix)]; ok {
+				limit = n
+			}
+			if limit > 0 {
+				if r.ContentLength > limit {
+					http.Error(w, "Тело запроса превышает допустимый размер", http.StatusRequestEntityTooLarge)
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimit ограничивает частоту запросов клиента: ключа доступа или токена,
+// а без аутентификации — IP-адреса.
+func (api *API) rateLimit(next http.Handler) http.Handler {
+	return limitRate(api.limits.limiter, clientKey, next)
+}
+
+// ipRateLimit ограничивает частоту запросов с IP-адреса до аутентификации, чтобы запросы
+// без учётных данных или с неверными не позволяли перебирать ключи и нагружать базу данных.
+func (api *API) ipRateLimit(next http.Handler) http.Handler {
+	return limitRate(api.limits.ipLimiter, ipKey, next)
+}
+
+// limitRate ограничивает частоту запросов с ключом key. Состояние ограничения передаётся
+// в заголовках RateLimit-*, при превышении сервер отвечает 429 с заголовком Retry-After.
+func limitRate(l *rateLimiter, key func(r *http.Request) string, next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, remaining, reset, retryAfter := l.allow(key(r), time.Now())
+
+		h := w.Header()
+		h.Set("RateLimit-Policy", l.policy)
+		h.Set("RateLimit-Limit", strconv.Itoa(l.burst))
+		h.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(seconds(reset)))
+		if !ok {
+			h.Set("Retry-After", strconv.Itoa(seconds(retryAfter)))
+			http.Error(w, "Превышено ограничение частоты запросов", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitConcurrency отклоняет с ответом 429 запросы к маршруту, если он уже выполняет
+// заданное количество запросов.
+func (api *API) limitConcurrency(prefix string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if len(api.limits.concurrency) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sem, ok := api.limits.concurrency[routeKey(r, prefix)]
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+				next.ServeHTTP(w, r)
+			default:
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, "Превышено количество одновременных запросов", http.StatusTooManyRequests)
+			}
+		})
+	}
+}
+
+// clientKey возвращает ключ клиента для ограничения частоты запросов.
+func clientKey(r *http.Request) string {
+	if p, ok := auth.PrincipalFrom(r.Context()); ok {
+		return p.Method + ":" + p.Tenant + ":" + p.Subject
+	}
+	return ipKey(r)
+}
+
+// ipKey возвращает ключ IP-адреса клиента для ограничения частоты запросов.
+func ipKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// seconds округляет длительность вверх до целых секунд.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// rateLimiter Ограничение частоты запросов по алгоритму token bucket:
+// у каждого клиента до burst запросов, которые восстанавливаются со скоростью rate в секунду.
+type rateLimiter struct {
+	rate   float64
+	burst  int
+	policy string // значение заголовка RateLimit-Policy
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// bucket Запас запросов клиента на момент updated.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func newRateLimiter(limit int, window time.Duration, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:    float64(limit) / window.Seconds(),
+		burst:   burst,
+		policy:  fmt.Sprintf("%d;w=%d;burst=%d", limit, seconds(window), burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// allow расходует запрос клиента key. Возвращает, разрешён ли запрос, остаток запросов,
+// время до полного восстановления запаса и время до следующего разрешённого запроса.
+func (l *rateLimiter) allow(key string, now time.Time) (bool, int, time.Duration, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.burst), b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	var retryAfter time.Duration
+	if allowed {
+		b.tokens--
+	} else {
+		retryAfter = l.duration(1 - b.tokens)
+	}
+
+	return allowed, int(b.tokens), l.duration(float64(l.burst) - b.tokens), retryAfter
+}
+
+// sweep удаляет клиентов с полностью восстановленным запасом не чаще раза в минуту,
+// чтобы размер таблицы не рос с числом клиентов.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	full := l.duration(float64(l.burst))
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) >= full {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// duration возвращает время восстановления tokens запросов.
+func (l *rateLimiter) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
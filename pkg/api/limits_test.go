@@ -0,0 +1,217 @@
+package api
+
+import (
+	"context"
+	"github.com/zatrasz75/Service/configs"
+	"github.com/zatrasz75/Service/pkg/auth"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clock Время начала проверок ограничения частоты; время задаётся явно, без time.Now.
+var clock = time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+func TestRateLimiterBurst(t *testing.T) {
+	l := newRateLimiter(60, time.Minute, 3)
+
+	for i := 2; i >= 0; i-- {
+		ok, remaining, _, _ := l.allow("a", clock)
+		if !ok || remaining != i {
+			t.Fatalf("запрос %d: разрешён %v, остаток %d, ожидался %d", 3-i, ok, remaining, i)
+		}
+	}
+	ok, remaining, reset, retryAfter := l.allow("a", clock)
+	if ok || remaining != 0 {
+		t.Fatalf("запрос сверх burst: разрешён %v, остаток %d", ok, remaining)
+	}
+	if retryAfter != time.Second || reset != 3*time.Second {
+		t.Errorf("retryAfter %v, reset %v, ожидались 1s и 3s", retryAfter, reset)
+	}
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	l := newRateLimiter(60, time.Minute, 2)
+	l.allow("a", clock)
+	l.allow("a", clock)
+
+	if ok, _, _, retryAfter := l.allow("a", clock.Add(500*time.Millisecond)); ok || retryAfter != 500*time.Millisecond {
+		t.Fatalf("через 0.5s: разрешён %v, retryAfter %v", ok, retryAfter)
+	}
+	if ok, remaining, _, _ := l.allow("a", clock.Add(time.Second)); !ok || remaining != 0 {
+		t.Fatalf("через 1s: разрешён %v, остаток %d", ok, remaining)
+	}
+	// Запас не превышает burst, сколько бы времени ни прошло.
+	if ok, remaining, _, _ := l.allow("a", clock.Add(time.Hour)); !ok || remaining != 1 {
+		t.Fatalf("через час: разрешён %v, остаток %d, ожидался 1", ok, remaining)
+	}
+}
+
+func TestRateLimiterKeys(t *testing.T) {
+	l := newRateLimiter(1, time.Minute, 1)
+	if ok, _, _, _ := l.allow("a", clock); !ok {
+		t.Fatal("первый запрос клиента a отклонён")
+	}
+	if ok, _, _, _ := l.allow("a", clock); ok {
+		t.Fatal("второй запрос клиента a разрешён")
+	}
+	if ok, _, _, _ := l.allow("b", clock); !ok {
+		t.Fatal("запрос клиента b отклонён из-за запаса клиента a")
+	}
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	l := newRateLimiter(60, time.Minute, 2)
+	l.allow("a", clock)
+	l.allow("b", clock.Add(2*time.Minute-time.Second))
+
+	// Запас a восстановлен, запас b ещё нет.
+	l.allow("c", clock.Add(2*time.Minute))
+	if _, ok := l.buckets["a"]; ok {
+		t.Error("клиент a с восстановленным запасом не удалён")
+	}
+	if _, ok := l.buckets["b"]; !ok {
+		t.Error("клиент b удалён до восстановления запаса")
+	}
+}
+
+func TestLimitRate(t *testing.T) {
+	h := limitRate(newRateLimiter(1, time.Minute, 1), ipKey, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("первый запрос: код %d", w.Code)
+	}
+	if got := w.Header().Get("RateLimit-Policy"); got != "1;w=60;burst=1" {
+		t.Errorf("RateLimit-Policy %q", got)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Fatalf("второй запрос: код %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// Запросы с другого адреса ограничиваются отдельно.
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "192.0.2.2:1234"
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("запрос с другого адреса: код %d", w.Code)
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	if got := clientKey(r); got != "ip:192.0.2.1" {
+		t.Errorf("без аутентификации: %q", got)
+	}
+
+	r = r.WithContext(auth.WithPrincipal(r.Context(), auth.Principal{Subject: "billing", Method: "api_key", Tenant: "acme"}))
+	if got := clientKey(r); got != "api_key:acme:billing" {
+		t.Errorf("с аутентификацией: %q", got)
+	}
+	if got := ipKey(r); got != "ip:192.0.2.1" {
+		t.Errorf("ipKey: %q", got)
+	}
+}
+
+func TestParseRouteLimits(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    map[string]int64
+		wantErr bool
+	}{
+		{in: "", want: map[string]int64{}},
+		{in: "get /data/export=4; POST /data/import = 2 ;", want: map[string]int64{"GET /data/export": 4, "POST /data/import": 2}},
+		{in: "GET /data=0", want: map[string]int64{"GET /data": 0}},
+		{in: "GET /data", wantErr: true},
+		{in: "/data=1", wantErr: true},
+		{in: "GET data=1", wantErr: true},
+		{in: "GET /data=много", wantErr: true},
+		{in: "GET /data=-1", wantErr: true},
+		{in: "GET /data=1;POST /data", want: map[string]int64{"GET /data": 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseRouteLimits(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: ошибка %v", tt.in, err)
+		}
+		if tt.want != nil && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: получено %v, ожидалось %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestNewInvalidLimits проверяет, что сервер не создаётся с некорректными правилами маршрутов.
+func TestNewInvalidLimits(t *testing.T) {
+	for _, server := range []configs.Server{
+		{BodyLimits: "POST /data=много"},
+		{ConcurrencyLimits: "GET /data/export"},
+	} {
+		if _, err := New(&configs.Config{Server: server}, nil, nil, nil, nil, nil); err == nil {
+			t.Errorf("%+v: ошибка не возвращена", server)
+		}
+	}
+}
+
+// blockingDB Хранилище, выборка которого ждёт закрытия release.
+type blockingDB struct {
+	specDB
+	started chan struct{}
+	release chan struct{}
+}
+
+func (db blockingDB) Select(ctx context.Context, filter storage.Filter, page, pageSize int) ([]storage.UsersData, error) {
+	db.started <- struct{}{}
+	<-db.release
+	return nil, nil
+}
+
+func TestLimitConcurrency(t *testing.T) {
+	db := blockingDB{started: make(chan struct{}), release: make(chan struct{})}
+	api, err := New(&configs.Config{Server: configs.Server{ConcurrencyLimits: "GET /data=1"}}, db, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		api.r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, currentPrefix+"/data", nil))
+		done <- w.Code
+	}()
+	<-db.started
+
+	w := httptest.NewRecorder()
+	api.r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, currentPrefix+"/data", nil))
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("второй одновременный запрос: код %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// Маршруты без ограничения не ждут.
+	w = httptest.NewRecorder()
+	api.r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, currentPrefix+"/data/1", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("запрос к другому маршруту: код %d: %s", w.Code, strings.TrimSpace(w.Body.String()))
+	}
+
+	close(db.release)
+	if code := <-done; code != http.StatusOK {
+		t.Fatalf("первый запрос: код %d", code)
+	}
+
+	go func() { <-db.started }()
+	w = httptest.NewRecorder()
+	api.r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, currentPrefix+"/data", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("запрос после завершения первого: код %d", w.Code)
+	}
+}
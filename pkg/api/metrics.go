@@ -0,0 +1,79 @@
+package api
+
+import (
+	"bufio"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/zatrasz75/Service/pkg/metrics"
+	"net"
+	"net/http"
+	"time"
+)
+
+// metricsEndpoints регистрирует маршрут метрик Prometheus. Как и документация,
+// он доступен без аутентификации.
+func (api *API) metricsEndpoints() {
+	api.r.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
+}
+
+// healthEndpoints регистрирует маршруты проверки состояния для оркестратора.
+// Они доступны без аутентификации и ограничений запросов.
+func (api *API) healthEndpoints() {
+	api.r.HandleFunc("/healthz", api.server.Healthz).Methods(http.MethodGet)
+	api.r.HandleFunc("/readyz", api.server.Readyz).Methods(http.MethodGet)
+}
+
+// instrument учитывает в метриках количество и время обработки запросов
+// по шаблону маршрута, методу и статусу ответа.
+func (api *API) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		route, _ := mux.CurrentRoute(r).GetPathTemplate()
+		metrics.ObserveHTTP(routeVarRegex.ReplaceAllString(route, "{$1}"), r.Method, rec.status, time.Since(start))
+	})
+}
+
+// statusRecorder запоминает статус ответа. Поддерживает потоковую передачу (SSE)
+// и перехват соединения (WebSocket).
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *statusRecorder) Flush() {
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (rec *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rec.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("соединение не поддерживает перехват")
+	}
+	rec.status = http.StatusSwitchingProtocols
+	rec.wroteHeader = true
+	return h.Hijack()
+}
+
+// Unwrap возвращает исходный ResponseWriter для http.ResponseController.
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
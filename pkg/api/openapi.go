@@ -0,0 +1,198 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	swaggerFiles "github.com/swaggo/files/v2"
+	"github.com/zatrasz75/Service/pkg/feed"
+	"github.com/zatrasz75/Service/pkg/service"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// openapiSpec спецификация OpenAPI 3 для всех маршрутов API.
+//
+//go:embed openapi.json
+var openapiSpec []byte
+
+// swaggerInitializer настраивает Swagger UI на спецификацию сервиса.
+const swaggerInitializer = `window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: "/openapi.json",
+    dom_id: '#swagger-ui',
+    deepLinking: true,
+    presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+    plugins: [SwaggerUIBundle.plugins.DownloadUrl],
+    layout: "StandaloneLayout"
+  });
+};
+`
+
+// specSchemas Схемы спецификации и соответствующие им типы ответов.
+// Поля схем сверяются с JSON-тегами типов в CheckSpec.
+var specSchemas = map[string]interface{}{
+	"Data":               storage.Data{},
+	"UsersData":          storage.UsersData{},
+	"HistoryEntry":       storage.HistoryEntry{},
+	"Stats":              storage.Stats{},
+	"GroupCount":         storage.GroupCount{},
+	"AgeBucket":          storage.AgeBucket{},
+	"AgeStats":           storage.AgeStats{},
+	"ImportJob":          storage.ImportJob{},
+	"ImportRejection":    storage.ImportRejection{},
+	"Delivery":           service.Delivery{},
+	"PersonEvent":        feed.Event{},
+	"Webhook":            storage.Webhook{},
+	"WebhookDelivery":    storage.WebhookDelivery{},
+	"WebhookAttempt":     storage.WebhookAttempt{},
+	"APIKey":             storage.APIKey{},
+	"EnrichmentSettings": storage.EnrichmentSettings{},
+}
+
+// routeVarRegex переменная маршрута с регулярным выражением, например {id:[0-9]+}.
+var routeVarRegex = regexp.MustCompile(`\{([^}:]+):[^}]+\}`)
+
+// docsEndpoints регистрирует спецификацию и Swagger UI.
+func (api *API) docsEndpoints() {
+	api.r.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openapiSpec)
+	}).Methods(http.MethodGet)
+
+	api.r.Handle("/docs", http.RedirectHandler("/docs/", http.StatusMovedPermanently)).Methods(http.MethodGet)
+	api.r.HandleFunc("/docs/swagger-initializer.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/javascript")
+		w.Write([]byte(swaggerInitializer))
+	}).Methods(http.MethodGet)
+	api.r.PathPrefix("/docs/").Handler(http.StripPrefix("/docs/", http.FileServer(http.FS(swaggerFiles.FS)))).Methods(http.MethodGet)
+}
+
+// specDocument Часть спецификации, необходимая для проверки.
+type specDocument struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+// CheckSpec сверяет спецификацию OpenAPI с зарегистрированными маршрутами и типами ответов.
+// Возвращает ошибку со списком расхождений.
+func (api *API) CheckSpec() error {
+	var doc specDocument
+	if err := json.Unmarshal(openapiSpec, &doc); err != nil {
+		return fmt.Errorf("некорректная спецификация: %w", err)
+	}
+
+	specOps := make(map[string]bool)
+	for path, item := range doc.Paths {
+		for method := range item {
+			if method == "parameters" {
+				continue
+			}
+			specOps[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	routeOps := make(map[string]bool)
+	err := api.r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		tpl, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		// Спецификация описывает текущую версию относительно servers.url,
+		// маршруты без версии и документация не проверяются.
+		if !strings.HasPrefix(tpl, currentPrefix+"/") {
+			return nil
+		}
+		path := routeVarRegex.ReplaceAllString(strings.TrimPrefix(tpl, currentPrefix), "{$1}")
+		for _, m := range methods {
+			routeOps[m+" "+path] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	for op := range routePermissions {
+		if !routeOps[op] {
+			problems = append(problems, "разрешение задано для незарегистрированного маршрута: "+op)
+		}
+	}
+	for op := range routeOps {
+		if !specOps[op] {
+			problems = append(problems, "маршрут не описан в спецификации: "+op)
+		}
+	}
+	for op := range specOps {
+		if !routeOps[op] {
+			problems = append(problems, "операция спецификации не зарегистрирована: "+op)
+		}
+	}
+
+	for name, v := range specSchemas {
+		schema, ok := doc.Components.Schemas[name]
+		if !ok {
+			problems = append(problems, "схема отсутствует в спецификации: "+name)
+			continue
+		}
+		fields := jsonFields(reflect.TypeOf(v))
+		for field := range fields {
+			if _, ok := schema.Properties[field]; !ok {
+				problems = append(problems, fmt.Sprintf("схема %s: нет свойства %s", name, field))
+			}
+		}
+		for prop := range schema.Properties {
+			if !fields[prop] {
+				problems = append(problems, fmt.Sprintf("схема %s: лишнее свойство %s", name, prop))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("спецификация расходится с API:\n%s", strings.Join(problems, "\n"))
+	}
+
+	return nil
+}
+
+// jsonFields возвращает имена JSON-полей структуры, включая поля встроенных структур.
+func jsonFields(t reflect.Type) map[string]bool {
+	fields := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for k := range jsonFields(f.Type) {
+				fields[k] = true
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = true
+	}
+	return fields
+}
@@ -0,0 +1,315 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/zatrasz75/Service/configs"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCheckSpec проверяет, что спецификация описывает все маршруты и типы ответов API.
+func TestCheckSpec(t *testing.T) {
+	api, err := New(&configs.Config{}, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = api.CheckSpec(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestResponsesMatchSpec проверяет, что ответы обработчиков соответствуют схемам спецификации.
+func TestResponsesMatchSpec(t *testing.T) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(openapiSpec, &doc); err != nil {
+		t.Fatal(err)
+	}
+	api, err := New(&configs.Config{}, specDB{}, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method string
+		path   string // путь запроса относительно currentPrefix
+		op     string // путь операции в спецификации
+	}{
+		{http.MethodGet, "/data?page=1&pageSize=2", "/data"},
+		{http.MethodGet, "/data?page=2&pageSize=2", "/data"},
+		{http.MethodGet, "/data/1", "/data/{id}"},
+		{http.MethodGet, "/data/1/history", "/data/{id}/history"},
+		{http.MethodGet, "/data/search?q=Иван", "/data/search"},
+		{http.MethodGet, "/data/stats", "/data/stats"},
+		{http.MethodGet, "/webhooks", "/webhooks"},
+		{http.MethodGet, "/webhooks/1", "/webhooks/{id}"},
+		{http.MethodGet, "/admin/api-keys", "/admin/api-keys"},
+		{http.MethodGet, "/admin/enrichment", "/admin/enrichment"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.op, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			api.r.ServeHTTP(w, httptest.NewRequest(tt.method, currentPrefix+tt.path, nil))
+
+			schema, err := responseSchema(doc, tt.op, tt.method, w.Code)
+			if err != nil {
+				t.Fatalf("%v, ответ: %s", err, w.Body.String())
+			}
+			var body interface{}
+			if err = json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("ответ не JSON: %v: %s", err, w.Body.String())
+			}
+			if problems := validate(doc, schema, body, "$"); len(problems) > 0 {
+				t.Errorf("ответ не соответствует схеме:\n%s", strings.Join(problems, "\n"))
+			}
+		})
+	}
+}
+
+// TestGetDataPagination проверяет, что GET /data передаёт в хранилище параметры page и pageSize.
+func TestGetDataPagination(t *testing.T) {
+	api, err := New(&configs.Config{}, specDB{}, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"page=1&pageSize=2", []int{1, 2}},
+		{"page=2&pageSize=2", []int{3}},
+		{"page=3&pageSize=2", nil},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		api.r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, currentPrefix+"/data?"+tt.query, nil))
+
+		var data []storage.UsersData
+		if err = json.Unmarshal(w.Body.Bytes(), &data); err != nil {
+			t.Fatalf("%s: %v: %s", tt.query, err, w.Body.String())
+		}
+		var got []int
+		for _, d := range data {
+			got = append(got, d.ID)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: получены записи %v, ожидались %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+// responseSchema возвращает схему ответа application/json операции спецификации.
+func responseSchema(doc map[string]interface{}, path, method string, status int) (map[string]interface{}, error) {
+	op, ok := lookup(doc, "paths", path, strings.ToLower(method)).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("операция %s %s не описана", method, path)
+	}
+	schema, ok := lookup(op, "responses", strconv.Itoa(status), "content", "application/json", "schema").(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("ответ %d операции %s %s не описан", status, method, path)
+	}
+	return schema, nil
+}
+
+// lookup возвращает вложенное значение JSON по ключам.
+func lookup(v interface{}, keys ...string) interface{} {
+	for _, k := range keys {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[k]
+	}
+	return v
+}
+
+// resolve заменяет ссылку $ref схемой из components и объединяет свойства allOf.
+func resolve(doc, schema map[string]interface{}) map[string]interface{} {
+	if ref, ok := schema["$ref"].(string); ok {
+		target, _ := lookup(doc, strings.Split(strings.TrimPrefix(ref, "#/"), "/")...).(map[string]interface{})
+		return resolve(doc, target)
+	}
+	all, ok := schema["allOf"].([]interface{})
+	if !ok {
+		return schema
+	}
+	props := make(map[string]interface{})
+	for _, s := range all {
+		sub, _ := s.(map[string]interface{})
+		for k, v := range resolve(doc, sub)["properties"].(map[string]interface{}) {
+			props[k] = v
+		}
+	}
+	return map[string]interface{}{"type": "object", "properties": props}
+}
+
+// validate проверяет значение v по схеме и возвращает расхождения с путём path.
+func validate(doc, schema map[string]interface{}, v interface{}, path string) []string {
+	schema = resolve(doc, schema)
+	if v == nil {
+		if schema["nullable"] == true {
+			return nil
+		}
+		return []string{path + ": null не допускается"}
+	}
+
+	var problems []string
+	switch schema["type"] {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return []string{path + ": ожидается объект"}
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			prop, ok := props[k].(map[string]interface{})
+			if !ok {
+				if schema["additionalProperties"] == nil && props != nil {
+					problems = append(problems, path+"."+k+": свойство не описано")
+				}
+				continue
+			}
+			if prop["writeOnly"] == true {
+				problems = append(problems, path+"."+k+": writeOnly свойство в ответе")
+			}
+			problems = append(problems, validate(doc, prop, obj[k], path+"."+k)...)
+		}
+		required, _ := schema["required"].([]interface{})
+		for _, r := range required {
+			if _, ok := obj[r.(string)]; !ok {
+				problems = append(problems, fmt.Sprintf("%s.%s: обязательное свойство отсутствует", path, r))
+			}
+		}
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			return []string{path + ": ожидается массив"}
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		for i, item := range arr {
+			problems = append(problems, validate(doc, items, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return []string{path + ": ожидается строка"}
+		}
+		if enum, ok := schema["enum"].([]interface{}); ok && !contains(enum, s) {
+			problems = append(problems, fmt.Sprintf("%s: значение %q не входит в enum", path, s))
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				problems = append(problems, path+": ожидается date-time")
+			}
+		}
+	case "integer":
+		if n, ok := v.(float64); !ok || n != float64(int64(n)) {
+			return []string{path + ": ожидается целое число"}
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return []string{path + ": ожидается число"}
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return []string{path + ": ожидается boolean"}
+		}
+	}
+	return problems
+}
+
+func contains(values []interface{}, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// specDB Хранилище с постоянными данными для проверки ответов. Методы, не используемые
+// в проверке, не реализованы.
+type specDB struct {
+	storage.Database
+}
+
+var (
+	specTime   = time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	specPerson = storage.UsersData{ID: 1, Name: "Иван", Surname: "Иванов", Patronymic: "Иванович",
+		Age: 42, Gender: "male", Nationality: "RU", Version: 3, CreatedAt: specTime, UpdatedAt: specTime}
+)
+
+func (specDB) Select(ctx context.Context, filter storage.Filter, page, pageSize int) ([]storage.UsersData, error) {
+	deleted, other := specPerson, specPerson
+	deleted.ID, deleted.DeletedAt = 2, &specTime
+	other.ID = 3
+	data := []storage.UsersData{specPerson, deleted, other}
+
+	from := (page - 1) * pageSize
+	if from >= len(data) {
+		return nil, nil
+	}
+	return data[from:min(from+pageSize, len(data))], nil
+}
+
+func (specDB) GetDataByID(ctx context.Context, id int) (storage.UsersData, error) {
+	return specPerson, nil
+}
+
+func (specDB) History(ctx context.Context, id int) ([]storage.HistoryEntry, error) {
+	values, _ := json.Marshal(specPerson)
+	return []storage.HistoryEntry{
+		{ID: 10, PersonID: 1, TenantID: storage.DefaultTenant, Operation: storage.OpInsert, NewValues: values,
+			Source: storage.SourceREST, ChangedAt: specTime},
+		{ID: 11, PersonID: 1, TenantID: storage.DefaultTenant, Operation: storage.OpUpdate, OldValues: values, NewValues: values,
+			Source: storage.SourceKafka, Actor: "FIO", ChangedAt: specTime},
+	}, nil
+}
+
+func (specDB) Search(ctx context.Context, query string, filter storage.Filter, limit int) ([]storage.SearchResult, error) {
+	return []storage.SearchResult{{UsersData: specPerson, Score: 0.87}}, nil
+}
+
+func (specDB) Stats(ctx context.Context, filter storage.Filter, bucketWidth int) (storage.Stats, error) {
+	return storage.Stats{
+		Total:         2,
+		ByGender:      []storage.GroupCount{{Value: "male", Count: 2}},
+		ByNationality: []storage.GroupCount{{Value: "RU", Count: 2}},
+		ByAge:         []storage.AgeBucket{{From: 40, To: 49, Count: 2}},
+		Age:           storage.AgeStats{Count: 2, Min: 42, Max: 42, Avg: 42, P50: 42, P90: 42, P99: 42},
+	}, nil
+}
+
+func (specDB) ListWebhooks(ctx context.Context) ([]storage.Webhook, error) {
+	hook, _ := specDB{}.GetWebhook(ctx, 1)
+	return []storage.Webhook{hook}, nil
+}
+
+func (specDB) GetWebhook(ctx context.Context, id int64) (storage.Webhook, error) {
+	return storage.Webhook{ID: id, URL: "https://example.com/hook", EventTypes: []string{storage.EventPersonCreated},
+		Secret: "secret", Active: true, CreatedAt: specTime, UpdatedAt: specTime}, nil
+}
+
+func (specDB) ListAPIKeys(ctx context.Context) ([]storage.APIKey, error) {
+	return []storage.APIKey{{ID: 1, Name: "billing", Prefix: "sk_1234", Role: "reader", TenantID: storage.DefaultTenant,
+		CreatedAt: specTime, LastUsedAt: &specTime}}, nil
+}
+
+func (specDB) EnrichmentSettings(ctx context.Context) (storage.EnrichmentSettings, error) {
+	settings := storage.DefaultEnrichment
+	settings.CountryID, settings.APIKey, settings.UpdatedAt = "RU", "key", specTime
+	return settings, nil
+}
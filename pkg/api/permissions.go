@@ -0,0 +1,69 @@
+package api
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/zatrasz75/Service/pkg/auth"
+	"net/http"
+	"strings"
+)
+
+// routePermissions разрешения маршрутов, отличающиеся от разрешения по методу запроса
+// (auth.DefaultPermission). Ключ — метод и путь без префикса версии, как в спецификации.
+// Правила из AUTH_ROUTE_PERMISSIONS имеют приоритет.
+var routePermissions = map[string]auth.Permission{
+	"POST /data/bulk":   auth.PermAdmin,
+	"PATCH /data/bulk":  auth.PermAdmin,
+	"DELETE /data/bulk": auth.PermAdmin,
+	"POST /data/import": auth.PermAdmin,
+
+	// Восстановление отменяет удаление, поэтому требует того же разрешения.
+	"POST /data/{id}/restore": auth.PermDelete,
+
+	"GET /webhooks":                                       auth.PermAdmin,
+	"POST /webhooks":                                      auth.PermAdmin,
+	"GET /webhooks/{id}":                                  auth.PermAdmin,
+	"PUT /webhooks/{id}":                                  auth.PermAdmin,
+	"DELETE /webhooks/{id}":                               auth.PermAdmin,
+	"POST /webhooks/{id}/replay":                          auth.PermAdmin,
+	"GET /webhooks/{id}/deliveries":                       auth.PermAdmin,
+	"GET /webhooks/{id}/deliveries/{delivery_id}":         auth.PermAdmin,
+	"POST /webhooks/{id}/deliveries/{delivery_id}/replay": auth.PermAdmin,
+
+	"GET /admin/api-keys":         auth.PermAdmin,
+	"POST /admin/api-keys":        auth.PermAdmin,
+	"DELETE /admin/api-keys/{id}": auth.PermAdmin,
+	"GET /admin/enrichment":       auth.PermAdmin,
+	"PUT /admin/enrichment":       auth.PermAdmin,
+}
+
+// routeKey возвращает метод и путь маршрута запроса без префикса версии.
+func routeKey(r *http.Request, prefix string) string {
+	tpl, _ := mux.CurrentRoute(r).GetPathTemplate()
+	return r.Method + " " + routeVarRegex.ReplaceAllString(strings.TrimPrefix(tpl, prefix), "{$1}")
+}
+
+// permission возвращает разрешение, требуемое маршрутом.
+func (api *API) permission(route, method string) auth.Permission {
+	if p, ok := api.auth.Permission(route); ok {
+		return p
+	}
+	if p, ok := routePermissions[route]; ok {
+		return p
+	}
+	return auth.DefaultPermission(method)
+}
+
+// authorize отклоняет с ответом 403 запросы клиентов, роль которых не имеет
+// разрешения маршрута. Без аутентификации запросы не проверяются.
+func (api *API) authorize(prefix string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := auth.PrincipalFrom(r.Context())
+			if ok && !p.Can(api.permission(routeKey(r, prefix), r.Method)) {
+				http.Error(w, "Недостаточно прав", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
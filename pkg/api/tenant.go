@@ -0,0 +1,25 @@
+package api
+
+import (
+	"errors"
+	"github.com/zatrasz75/Service/pkg/auth"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"net/http"
+)
+
+// tenant передаёт в контексте запроса арендатора из учётных данных или заголовка X-Tenant-ID.
+func (api *API) tenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant, err := auth.Tenant(r.Context(), r.Header.Get("X-Tenant-ID"))
+		if errors.Is(err, auth.ErrTenantMismatch) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(storage.WithTenant(r.Context(), tenant)))
+	})
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+// currentPrefix префикс текущей версии API.
+const currentPrefix = "/api/v1"
+
+// version Версия API: префикс и функция регистрации её маршрутов.
+// Новая версия добавляется в versions со своими обработчиками и DTO
+// и работает одновременно с предыдущими.
+type version struct {
+	prefix string
+	routes func(r *mux.Router)
+}
+
+// versions возвращает все поддерживаемые версии API.
+func (api *API) versions() []version {
+	return []version{
+		{prefix: "/api/v1", routes: api.v1Routes},
+	}
+}
+
+// legacyRoutes регистрирует маршруты без версии. Они совпадают с /api/v1
+// и отдаются с заголовками Deprecation и Sunset.
+func (api *API) legacyRoutes(r *mux.Router) {
+	api.v1Routes(r)
+}
+
+// v1Routes регистрирует обработчики API версии 1.
+func (api *API) v1Routes(r *mux.Router) {
+	r.HandleFunc("/data", api.server.GetData).Methods(http.MethodGet)
+	r.HandleFunc("/data", api.server.AddData).Methods(http.MethodPost)
+	r.HandleFunc("/data/export", api.server.ExportData).Methods(http.MethodGet)
+	r.HandleFunc("/data/search", api.server.SearchData).Methods(http.MethodGet)
+	r.HandleFunc("/data/stats", api.server.GetStats).Methods(http.MethodGet)
+	r.HandleFunc("/data/stream", api.server.StreamData).Methods(http.MethodGet)
+	r.HandleFunc("/data/stream/ws", api.server.StreamDataWS).Methods(http.MethodGet)
+	r.HandleFunc("/data/import", api.server.ImportData).Methods(http.MethodPost)
+	r.HandleFunc("/data/import/{id:[0-9a-f]+}", api.server.GetImport).Methods(http.MethodGet)
+	r.HandleFunc("/data/bulk", api.server.BulkAddData).Methods(http.MethodPost)
+	r.HandleFunc("/data/bulk", api.server.BulkPartialUpdateData).Methods(http.MethodPatch)
+	r.HandleFunc("/data/bulk", api.server.BulkDeleteData).Methods(http.MethodDelete)
+	r.HandleFunc("/data/{id:[0-9]+}", api.server.GetDataByID).Methods(http.MethodGet)
+	r.HandleFunc("/data/{id:[0-9]+}", api.server.DeleteData).Methods(http.MethodDelete)
+	r.HandleFunc("/data/{id:[0-9]+}", api.server.UpdateData).Methods(http.MethodPut)
+	r.HandleFunc("/data/{id:[0-9]+}", api.server.PartialUpdateData).Methods(http.MethodPatch)
+	r.HandleFunc("/data/{id:[0-9]+}/restore", api.server.RestoreData).Methods(http.MethodPost)
+	r.HandleFunc("/data/{id:[0-9]+}/history", api.server.GetHistory).Methods(http.MethodGet)
+	r.HandleFunc("/fio", api.server.PublishFIO).Methods(http.MethodPost)
+	r.HandleFunc("/webhooks", api.server.ListWebhooks).Methods(http.MethodGet)
+	r.HandleFunc("/webhooks", api.server.CreateWebhook).Methods(http.MethodPost)
+	r.HandleFunc("/webhooks/{id:[0-9]+}", api.server.GetWebhook).Methods(http.MethodGet)
+	r.HandleFunc("/webhooks/{id:[0-9]+}", api.server.UpdateWebhook).Methods(http.MethodPut)
+	r.HandleFunc("/webhooks/{id:[0-9]+}", api.server.DeleteWebhook).Methods(http.MethodDelete)
+	r.HandleFunc("/webhooks/{id:[0-9]+}/replay", api.server.ReplayWebhook).Methods(http.MethodPost)
+	r.HandleFunc("/webhooks/{id:[0-9]+}/deliveries", api.server.GetWebhookDeliveries).Methods(http.MethodGet)
+	r.HandleFunc("/webhooks/{id:[0-9]+}/deliveries/{delivery_id:[0-9]+}", api.server.GetWebhookDelivery).Methods(http.MethodGet)
+	r.HandleFunc("/webhooks/{id:[0-9]+}/deliveries/{delivery_id:[0-9]+}/replay", api.server.ReplayWebhookDelivery).Methods(http.MethodPost)
+
+	r.HandleFunc("/admin/api-keys", api.server.ListAPIKeys).Methods(http.MethodGet)
+	r.HandleFunc("/admin/api-keys", api.server.CreateAPIKey).Methods(http.MethodPost)
+	r.HandleFunc("/admin/api-keys/{id:[0-9]+}", api.server.RevokeAPIKey).Methods(http.MethodDelete)
+	r.HandleFunc("/admin/enrichment", api.server.GetEnrichment).Methods(http.MethodGet)
+	r.HandleFunc("/admin/enrichment", api.server.SetEnrichment).Methods(http.MethodPut)
+}
+
+// deprecated добавляет к ответам маршрутов без версии заголовки устаревания
+// и ссылку на актуальную версию.
+func (api *API) deprecated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if api.cfg.LegacyDeprecation.IsZero() {
+			w.Header().Set("Deprecation", "true")
+		} else {
+			w.Header().Set("Deprecation", "@"+strconv.FormatInt(api.cfg.LegacyDeprecation.Unix(), 10))
+		}
+		if !api.cfg.LegacySunset.IsZero() {
+			w.Header().Set("Sunset", api.cfg.LegacySunset.UTC().Format(http.TimeFormat))
+		}
+		w.Header().Set("Link", "<"+currentPrefix+r.URL.Path+`>; rel="successor-version"`)
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// apiKeyPrefix начало каждого ключа доступа. Отличает ключи от токенов JWT.
+const apiKeyPrefix = "svc_"
+
+// GenerateAPIKey создаёт ключ доступа. Возвращает ключ, который показывается клиенту один раз,
+// его префикс для отображения в списке и хеш для хранения.
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	secret := make([]byte, 32)
+	if _, err = rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+	key = apiKeyPrefix + hex.EncodeToString(secret)
+	return key, key[:len(apiKeyPrefix)+8], HashAPIKey(key), nil
+}
+
+// HashAPIKey возвращает хеш ключа доступа. Ключ содержит 256 случайных бит,
+// поэтому медленная функция хеширования не требуется.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// isAPIKey сообщает, является ли значение ключом доступа.
+func isAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, apiKeyPrefix)
+}
@@ -0,0 +1,59 @@
+// Package auth аутентификация запросов по ключам доступа API и токенам JWT.
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Способы аутентификации.
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+var (
+	// ErrNoCredentials запрос не содержит ключа доступа или токена.
+	ErrNoCredentials = errors.New("требуется аутентификация")
+	// ErrInvalidCredentials ключ доступа или токен недействителен.
+	ErrInvalidCredentials = errors.New("недействительные учётные данные")
+)
+
+// Principal Аутентифицированный клиент.
+type Principal struct {
+	Subject string                 // имя ключа доступа или claim sub токена
+	Method  string                 // способ аутентификации
+	Role    string                 // роль клиента, пустая роль не имеет разрешений
+	Tenant  string                 // арендатор ключа или claim токена, без claim — storage.DefaultTenant
+	Claims  map[string]interface{} // claims токена JWT
+
+	HiddenFields map[string]bool // поля записей, скрытые от роли
+}
+
+type principalKey struct{}
+
+// WithPrincipal возвращает контекст с аутентифицированным клиентом.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom извлекает аутентифицированного клиента из контекста.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// Credentials возвращает ключ доступа из заголовка X-API-Key или токен из заголовка
+// Authorization: Bearer.
+func Credentials(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/zatrasz75/Service/configs"
+	"github.com/zatrasz75/Service/pkg/logger"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"net/http"
+)
+
+// jwtAlgorithms допустимые алгоритмы подписи токенов.
+var jwtAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// Claims токена по умолчанию.
+const (
+	defaultRolesClaim  = "roles"
+	defaultTenantClaim = "tenant_id"
+)
+
+// Authenticator Проверяет ключи доступа по хешам в базе данных и токены JWT по JWKS,
+// определяет роль клиента и разрешения маршрутов.
+type Authenticator struct {
+	enabled bool
+	db      storage.Database
+	jwks    *jwks // nil, если проверка токенов не настроена
+	parser  *jwt.Parser
+
+	rolesClaim  string
+	tenantClaim string
+	routes      map[string]Permission      // разрешения маршрутов, заданные в настройках
+	hidden      map[string]map[string]bool // скрытые поля по ролям
+}
+
+// New создаёт Authenticator. Если JWKS задан, набор ключей загружается сразу,
+// а недоступность URL только записывается в лог: загрузка повторится при первом токене.
+func New(cfg configs.Auth, db storage.Database) (*Authenticator, error) {
+	a := &Authenticator{enabled: cfg.Enabled, db: db, rolesClaim: cfg.JWTRolesClaim, tenantClaim: cfg.JWTTenantClaim}
+	if a.rolesClaim == "" {
+		a.rolesClaim = defaultRolesClaim
+	}
+	if a.tenantClaim == "" {
+		a.tenantClaim = defaultTenantClaim
+	}
+
+	var err error
+	if a.routes, err = parseRoutePermissions(cfg.RoutePermissions); err != nil {
+		return nil, err
+	}
+	if a.hidden, err = parseHiddenFields(cfg.HiddenFields); err != nil {
+		return nil, err
+	}
+
+	opts := []jwt.ParserOption{jwt.WithValidMethods(jwtAlgorithms), jwt.WithExpirationRequired()}
+	if cfg.JWTIssuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.JWTIssuer))
+	}
+	if cfg.JWTAudience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.JWTAudience))
+	}
+	a.parser = jwt.NewParser(opts...)
+
+	if cfg.JWKSURL != "" || cfg.JWKSFile != "" {
+		a.jwks = newJWKS(cfg.JWKSURL, cfg.JWKSFile, cfg.JWKSRefresh)
+		if err := a.jwks.reload(); err != nil {
+			if cfg.JWKSURL == "" {
+				return nil, err
+			}
+			logger.Error("Не удалось загрузить JWKS", err)
+		}
+	}
+
+	return a, nil
+}
+
+// Enabled сообщает, требуется ли аутентификация.
+func (a *Authenticator) Enabled() bool {
+	return a.enabled
+}
+
+// Permission возвращает разрешение, заданное в настройках для маршрута "METHOD /path".
+func (a *Authenticator) Permission(route string) (Permission, bool) {
+	p, ok := a.routes[route]
+	return p, ok
+}
+
+// Authenticate проверяет ключ доступа или токен JWT и определяет роль клиента.
+func (a *Authenticator) Authenticate(ctx context.Context, credential string) (Principal, error) {
+	p, err := a.authenticate(ctx, credential)
+	if err != nil {
+		return p, err
+	}
+	p.HiddenFields = a.hidden[p.Role]
+	return p, nil
+}
+
+func (a *Authenticator) authenticate(ctx context.Context, credential string) (Principal, error) {
+	if credential == "" {
+		return Principal{}, ErrNoCredentials
+	}
+
+	if isAPIKey(credential) {
+		// Арендатор запроса ещё не известен, ключ ищется среди ключей всех арендаторов.
+		key, err := a.db.APIKeyByHash(storage.WithTenant(ctx, storage.AllTenants), HashAPIKey(credential))
+		if errors.Is(err, storage.ErrNotFound) {
+			return Principal{}, ErrInvalidCredentials
+		}
+		if err != nil {
+			return Principal{}, err
+		}
+		return Principal{Subject: key.Name, Method: MethodAPIKey, Role: key.Role, Tenant: key.TenantID}, nil
+	}
+
+	if a.jwks == nil {
+		return Principal{}, ErrInvalidCredentials
+	}
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(credential, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return a.jwks.key(kid)
+	})
+	if err != nil {
+		return Principal{}, ErrInvalidCredentials
+	}
+	sub, _ := claims.GetSubject()
+	tenant, _ := claims[a.tenantClaim].(string)
+	if tenant != "" && !storage.ValidTenant(tenant) {
+		return Principal{}, ErrInvalidCredentials
+	}
+	// Токен без claim арендатора не даёт доступа к данным других арендаторов.
+	if tenant == "" {
+		tenant = storage.DefaultTenant
+	}
+
+	return Principal{Subject: sub, Method: MethodJWT, Role: highestRole(claims[a.rolesClaim]), Tenant: tenant, Claims: claims}, nil
+}
+
+// Middleware отклоняет запросы без действительного ключа доступа или токена
+// с ответом 401 и передаёт Principal в контексте запроса.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		p, err := a.Authenticate(r.Context(), Credentials(r))
+		if err != nil {
+			if !errors.Is(err, ErrNoCredentials) && !errors.Is(err, ErrInvalidCredentials) {
+				logger.Error("Ошибка при проверке учётных данных", err)
+				http.Error(w, "Ошибка при проверке учётных данных", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="service"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+	})
+}
@@ -0,0 +1,347 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/zatrasz75/Service/configs"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// keyDB Хранилище ключей доступа по хешам.
+type keyDB struct {
+	storage.Database
+	keys map[string]storage.APIKey
+}
+
+func (db keyDB) APIKeyByHash(ctx context.Context, hash string) (storage.APIKey, error) {
+	if storage.TenantFrom(ctx) != storage.AllTenants {
+		return storage.APIKey{}, errors.New("ключ ищется в пределах одного арендатора")
+	}
+	key, ok := db.keys[hash]
+	if !ok {
+		return storage.APIKey{}, storage.ErrNotFound
+	}
+	return key, nil
+}
+
+// testKey ключ подписи токенов и путь к файлу JWKS с его открытой частью.
+type testKey struct {
+	private *rsa.PrivateKey
+	jwks    string
+}
+
+func newTestKey(t *testing.T) testKey {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA", "kid": "test", "use": "sig",
+		"n": base64.RawURLEncoding.EncodeToString(private.N.Bytes()),
+		"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(private.E)).Bytes()),
+	}}}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err = os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return testKey{private: private, jwks: path}
+}
+
+// token подписывает токен с claims. Без exp токен действует час.
+func (k testKey) token(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	if _, ok := claims["exp"]; !ok {
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = "test"
+	s, err := tok.SignedString(k.private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestAPIKey(t *testing.T) {
+	key, prefix, hash, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^svc_[0-9a-f]{64}$`).MatchString(key) {
+		t.Errorf("некорректный формат ключа %q", key)
+	}
+	if prefix != key[:12] {
+		t.Errorf("префикс %q, ожидалось %q", prefix, key[:12])
+	}
+	if hash != HashAPIKey(key) || len(hash) != 64 {
+		t.Errorf("хеш %q не совпадает с HashAPIKey", hash)
+	}
+	if HashAPIKey(key+"0") == hash {
+		t.Error("хеши разных ключей совпадают")
+	}
+	if other, _, _, _ := GenerateAPIKey(); other == key {
+		t.Error("повторный ключ совпадает с предыдущим")
+	}
+	// Хранится SHA-256 ключа в шестнадцатеричном виде.
+	if got, want := HashAPIKey("svc_test"), "97da9667a2bfbbb015a093453022206e64f86667f14e59148ceb3d1315c46769"; got != want {
+		t.Errorf("HashAPIKey: %s, ожидалось %s", got, want)
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	key, _, hash, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := keyDB{keys: map[string]storage.APIKey{
+		hash: {Name: "billing", Role: RoleReader, TenantID: "acme"},
+	}}
+	a, err := New(configs.Auth{Enabled: true, HiddenFields: "reader=nationality"}, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := a.Authenticate(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Principal{Subject: "billing", Method: MethodAPIKey, Role: RoleReader, Tenant: "acme",
+		HiddenFields: map[string]bool{"nationality": true}}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("получено %+v, ожидалось %+v", p, want)
+	}
+
+	if _, err = a.Authenticate(context.Background(), ""); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("без ключа: ошибка %v", err)
+	}
+	if _, err = a.Authenticate(context.Background(), "svc_unknown"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("неизвестный ключ: ошибка %v", err)
+	}
+	// Без JWKS токены не принимаются.
+	if _, err = a.Authenticate(context.Background(), "eyJhbGciOiJSUzI1NiJ9.e30.sig"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("токен без JWKS: ошибка %v", err)
+	}
+}
+
+func TestAuthenticateJWT(t *testing.T) {
+	k := newTestKey(t)
+	other := newTestKey(t)
+	a, err := New(configs.Auth{Enabled: true, JWKSFile: k.jwks, JWTIssuer: "https://issuer", JWTAudience: "service"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := func(extra jwt.MapClaims) jwt.MapClaims {
+		claims := jwt.MapClaims{"sub": "user-1", "iss": "https://issuer", "aud": "service", "roles": []string{"reader", "editor"}}
+		for name, v := range extra {
+			claims[name] = v
+		}
+		return claims
+	}
+
+	p, err := a.Authenticate(context.Background(), k.token(t, valid(jwt.MapClaims{"tenant_id": "acme"})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Subject != "user-1" || p.Method != MethodJWT || p.Role != RoleEditor || p.Tenant != "acme" {
+		t.Errorf("получено %+v", p)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"истёк", k.token(t, valid(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}))},
+		{"без exp", func() string {
+			tok := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": "user-1", "iss": "https://issuer", "aud": "service"})
+			tok.Header["kid"] = "test"
+			s, _ := tok.SignedString(k.private)
+			return s
+		}()},
+		{"другой издатель", k.token(t, valid(jwt.MapClaims{"iss": "https://other"}))},
+		{"другая аудитория", k.token(t, valid(jwt.MapClaims{"aud": "other"}))},
+		{"чужой ключ", other.token(t, valid(nil))},
+		{"некорректный арендатор", k.token(t, valid(jwt.MapClaims{"tenant_id": "Acme Corp"}))},
+		{"алгоритм HS256", func() string {
+			s, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, valid(jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix()})).
+				SignedString([]byte("secret"))
+			return s
+		}()},
+		{"не токен", "not-a-token"},
+	}
+	for _, tt := range tests {
+		if _, err = a.Authenticate(context.Background(), tt.token); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("%s: ошибка %v, ожидалась %v", tt.name, err, ErrInvalidCredentials)
+		}
+	}
+}
+
+func TestHighestRole(t *testing.T) {
+	tests := []struct {
+		claim interface{}
+		want  string
+	}{
+		{"reader", RoleReader},
+		{"reader editor", RoleEditor},
+		{"admin,reader", RoleAdmin},
+		{[]interface{}{"reader", "admin", "editor"}, RoleAdmin},
+		{[]interface{}{"owner", 1, "reader"}, RoleReader},
+		{"owner", ""},
+		{"", ""},
+		{nil, ""},
+		{42.0, ""},
+	}
+	for _, tt := range tests {
+		if got := highestRole(tt.claim); got != tt.want {
+			t.Errorf("%v: роль %q, ожидалась %q", tt.claim, got, tt.want)
+		}
+	}
+}
+
+func TestCan(t *testing.T) {
+	tests := []struct {
+		role string
+		perm Permission
+		want bool
+	}{
+		{RoleReader, PermRead, true},
+		{RoleReader, PermWrite, false},
+		{RoleEditor, PermWrite, true},
+		{RoleEditor, PermDelete, false},
+		{RoleAdmin, PermDelete, true},
+		{RoleAdmin, PermAdmin, true},
+		{"", PermRead, false},
+		{"owner", PermRead, false},
+	}
+	for _, tt := range tests {
+		if got := (Principal{Role: tt.role}).Can(tt.perm); got != tt.want {
+			t.Errorf("%q %s: %v, ожидалось %v", tt.role, tt.perm, got, tt.want)
+		}
+	}
+	if !Allowed(context.Background(), PermAdmin) {
+		t.Error("без аутентификации разрешение не выдано")
+	}
+	if Allowed(WithPrincipal(context.Background(), Principal{Role: RoleEditor}), PermAdmin) {
+		t.Error("редактору выдано разрешение admin")
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	routes, err := parseRoutePermissions(" delete /data/{id}=write; POST /data/import=admin ;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Permission{"DELETE /data/{id}": PermWrite, "POST /data/import": PermAdmin}
+	if !reflect.DeepEqual(routes, want) {
+		t.Errorf("получено %v, ожидалось %v", routes, want)
+	}
+	for _, s := range []string{"DELETE /data/{id}", "/data=read", "GET data=read", "GET /data=owner"} {
+		if _, err = parseRoutePermissions(s); err == nil {
+			t.Errorf("%q: правило принято", s)
+		}
+	}
+
+	hidden, err := parseHiddenFields("reader=nationality, age;editor=gender")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantHidden := map[string]map[string]bool{
+		RoleReader: {"nationality": true, "age": true},
+		RoleEditor: {"gender": true},
+	}
+	if !reflect.DeepEqual(hidden, wantHidden) {
+		t.Errorf("получено %v, ожидалось %v", hidden, wantHidden)
+	}
+	for _, s := range []string{"owner=age", "reader", "reader=id", "reader=email"} {
+		if _, err = parseHiddenFields(s); err == nil {
+			t.Errorf("%q: правило принято", s)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	ctx := WithPrincipal(context.Background(), Principal{Role: RoleReader, HiddenFields: map[string]bool{"age": true, "nationality": true}})
+	d := storage.UsersData{ID: 1, Name: "Иван", Age: 42, Gender: "male", Nationality: "RU"}
+	Redact(ctx, &d)
+	if d.Age != 0 || d.Nationality != "" || d.Name != "Иван" || d.Gender != "male" {
+		t.Errorf("получено %+v", d)
+	}
+
+	values := RedactValues(ctx, []byte(`{"name":"Иван","age":42,"nationality":"RU"}`))
+	if string(values) != `{"name":"Иван"}` {
+		t.Errorf("получено %s", values)
+	}
+
+	f := RestrictFilter(WithPrincipal(context.Background(), Principal{Role: RoleReader, HiddenFields: map[string]bool{"gender": true}}),
+		storage.Filter{Gender: "male", IncludeDeleted: true})
+	if f != (storage.Filter{}) {
+		t.Errorf("фильтр %+v, ожидался пустой", f)
+	}
+	f = RestrictFilter(WithPrincipal(context.Background(), Principal{Role: RoleAdmin}), storage.Filter{Gender: "male", IncludeDeleted: true})
+	if f != (storage.Filter{Gender: "male", IncludeDeleted: true}) {
+		t.Errorf("фильтр администратора изменён: %+v", f)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	key, _, hash, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := New(configs.Auth{Enabled: true}, keyDB{keys: map[string]storage.APIKey{hash: {Name: "billing", Role: RoleAdmin, TenantID: "acme"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got Principal
+	h := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = PrincipalFrom(r.Context())
+	}))
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   int
+	}{
+		{"X-API-Key", http.Header{"X-Api-Key": {key}}, http.StatusOK},
+		{"Bearer", http.Header{"Authorization": {"bearer " + key}}, http.StatusOK},
+		{"без учётных данных", http.Header{}, http.StatusUnauthorized},
+		{"неизвестный ключ", http.Header{"X-Api-Key": {"svc_unknown"}}, http.StatusUnauthorized},
+		{"Basic", http.Header{"Authorization": {"Basic " + key}}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		got = Principal{}
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header = tt.header
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s: код %d, ожидался %d", tt.name, w.Code, tt.want)
+		}
+		if tt.want == http.StatusOK && got.Subject != "billing" {
+			t.Errorf("%s: клиент %+v не передан в контексте", tt.name, got)
+		}
+		if tt.want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: нет заголовка WWW-Authenticate", tt.name)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Ограничения обновления набора ключей.
+const (
+	defaultJWKSRefresh = time.Hour
+	minJWKSRefresh     = time.Minute // не чаще при запросе неизвестного kid
+	jwksFetchTimeout   = 10 * time.Second
+)
+
+// jwk Открытый ключ в формате JWK (RFC 7517).
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// jwks Набор открытых ключей для проверки подписи токенов. Загружается по URL
+// с периодическим обновлением или из локального файла. Если заданы оба источника,
+// файл используется, пока URL недоступен.
+type jwks struct {
+	url     string
+	file    string
+	refresh time.Duration
+	client  *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]interface{}
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+func newJWKS(url, file string, refresh time.Duration) *jwks {
+	if refresh <= 0 {
+		refresh = defaultJWKSRefresh
+	}
+	return &jwks{url: url, file: file, refresh: refresh, client: &http.Client{Timeout: jwksFetchTimeout}}
+}
+
+// key возвращает ключ с идентификатором kid. Пустой kid допустим, если в наборе один ключ.
+// Неизвестный kid приводит к внеочередному обновлению набора по URL.
+func (j *jwks) key(kid string) (interface{}, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	stale := time.Since(j.fetchedAt) > j.refresh
+	if j.keys == nil || (j.url != "" && stale) {
+		j.reload()
+	}
+	if k, ok := j.lookup(kid); ok {
+		return k, nil
+	}
+	if j.url != "" && time.Since(j.lastAttempt) > minJWKSRefresh {
+		j.reload()
+		if k, ok := j.lookup(kid); ok {
+			return k, nil
+		}
+	}
+
+	return nil, fmt.Errorf("ключ %q не найден в JWKS", kid)
+}
+
+func (j *jwks) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(j.keys) == 1 {
+		for _, k := range j.keys {
+			return k, true
+		}
+	}
+	k, ok := j.keys[kid]
+	return k, ok
+}
+
+// reload загружает набор ключей. При ошибке сохраняется предыдущий набор.
+func (j *jwks) reload() error {
+	j.lastAttempt = time.Now()
+
+	var data []byte
+	var err error
+	if j.url != "" {
+		data, err = j.fetch()
+	}
+	if (j.url == "" || err != nil) && j.file != "" {
+		data, err = os.ReadFile(j.file)
+	}
+	if err != nil {
+		return err
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+	j.keys = keys
+	j.fetchedAt = time.Now()
+	return nil
+}
+
+func (j *jwks) fetch() ([]byte, error) {
+	resp, err := j.client.Get(j.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS: ответ %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// parseJWKS разбирает набор ключей RSA и EC. Ключи других типов и ключи шифрования пропускаются.
+func parseJWKS(data []byte) (map[string]interface{}, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("JWKS: %w", err)
+	}
+
+	keys := make(map[string]interface{})
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("JWKS: ключ %q: %w", k.Kid, err)
+		}
+		if key != nil {
+			keys[k.Kid] = key
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS: нет ключей подписи")
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("неподдерживаемая кривая %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"net/http"
+	"strings"
+)
+
+// Роли клиентов.
+const (
+	RoleReader = "reader"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// Permission Разрешение, требуемое маршрутом.
+type Permission string
+
+// Разрешения.
+const (
+	PermRead   Permission = "read"   // чтение записей
+	PermWrite  Permission = "write"  // создание и изменение записей
+	PermDelete Permission = "delete" // удаление записей
+	PermAdmin  Permission = "admin"  // пакетные операции, подписки, ключи доступа
+)
+
+// rolePermissions разрешения каждой роли.
+var rolePermissions = map[string]map[Permission]bool{
+	RoleReader: {PermRead: true},
+	RoleEditor: {PermRead: true, PermWrite: true},
+	RoleAdmin:  {PermRead: true, PermWrite: true, PermDelete: true, PermAdmin: true},
+}
+
+// roleRank порядок ролей для выбора старшей из нескольких ролей токена.
+var roleRank = map[string]int{RoleReader: 1, RoleEditor: 2, RoleAdmin: 3}
+
+// ValidRole сообщает, является ли значение известной ролью.
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// Can сообщает, имеет ли роль клиента разрешение perm.
+func (p Principal) Can(perm Permission) bool {
+	return rolePermissions[p.Role][perm]
+}
+
+// Allowed сообщает, имеет ли клиент из контекста разрешение perm.
+// Без аутентификации клиенту доступны все разрешения.
+func Allowed(ctx context.Context, perm Permission) bool {
+	p, ok := PrincipalFrom(ctx)
+	return !ok || p.Can(perm)
+}
+
+// Hidden сообщает, скрыто ли поле записи field от роли клиента из контекста.
+func Hidden(ctx context.Context, field string) bool {
+	p, ok := PrincipalFrom(ctx)
+	return ok && p.HiddenFields[field]
+}
+
+// RestrictFilter убирает из фильтра условия, недоступные клиенту из контекста: фильтр по скрытому
+// от роли полю позволил бы узнать его значение, а удалённые записи доступны только с разрешением admin.
+func RestrictFilter(ctx context.Context, filter storage.Filter) storage.Filter {
+	if Hidden(ctx, "gender") {
+		filter.Gender = ""
+	}
+	if !Allowed(ctx, PermAdmin) {
+		filter.IncludeDeleted = false
+	}
+	return filter
+}
+
+// highestRole возвращает старшую известную роль из значения claim: строки
+// (роли через пробел или запятую) или массива строк.
+func highestRole(claim interface{}) string {
+	var roles []string
+	switch v := claim.(type) {
+	case string:
+		roles = strings.FieldsFunc(v, func(r rune) bool { return r == ' ' || r == ',' })
+	case []interface{}:
+		for _, r := range v {
+			if s, ok := r.(string); ok {
+				roles = append(roles, s)
+			}
+		}
+	}
+
+	best := ""
+	for _, r := range roles {
+		if roleRank[r] > roleRank[best] {
+			best = r
+		}
+	}
+	return best
+}
+
+// parseRoutePermissions разбирает правила вида "METHOD /path=permission;...",
+// например "DELETE /data/{id}=write;POST /data/import=admin".
+func parseRoutePermissions(s string) (map[string]Permission, error) {
+	rules := make(map[string]Permission)
+	for _, rule := range strings.Split(s, ";") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		route, perm, ok := strings.Cut(rule, "=")
+		method, path, okRoute := strings.Cut(strings.TrimSpace(route), " ")
+		if !ok || !okRoute || !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("некорректное правило маршрута %q", rule)
+		}
+		p := Permission(strings.TrimSpace(perm))
+		if !rolePermissions[RoleAdmin][p] {
+			return nil, fmt.Errorf("неизвестное разрешение %q в правиле %q", p, rule)
+		}
+		rules[strings.ToUpper(method)+" "+path] = p
+	}
+	return rules, nil
+}
+
+// parseHiddenFields разбирает поля, скрытые от ролей, в виде "role=field,field;...",
+// например "reader=nationality".
+func parseHiddenFields(s string) (map[string]map[string]bool, error) {
+	hidden := make(map[string]map[string]bool)
+	for _, rule := range strings.Split(s, ";") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		role, fields, ok := strings.Cut(rule, "=")
+		role = strings.TrimSpace(role)
+		if !ok || !ValidRole(role) {
+			return nil, fmt.Errorf("некорректное правило полей %q", rule)
+		}
+		hidden[role] = make(map[string]bool)
+		for _, f := range strings.Split(fields, ",") {
+			f = strings.TrimSpace(f)
+			if _, ok := storage.PatchableFields[f]; !ok {
+				return nil, fmt.Errorf("поле %q нельзя скрыть", f)
+			}
+			hidden[role][f] = true
+		}
+	}
+	return hidden, nil
+}
+
+// DefaultPermission разрешение маршрута по методу запроса: GET — чтение,
+// DELETE — удаление, остальные — изменение.
+func DefaultPermission(method string) Permission {
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		return PermRead
+	case http.MethodDelete:
+		return PermDelete
+	default:
+		return PermWrite
+	}
+}
+
+// Redact очищает поля записи, скрытые от роли клиента из контекста.
+func Redact(ctx context.Context, d *storage.UsersData) {
+	p, ok := PrincipalFrom(ctx)
+	if !ok {
+		return
+	}
+	for f := range p.HiddenFields {
+		switch f {
+		case "name":
+			d.Name = ""
+		case "surname":
+			d.Surname = ""
+		case "patronymic":
+			d.Patronymic = ""
+		case "age":
+			d.Age = 0
+		case "gender":
+			d.Gender = ""
+		case "nationality":
+			d.Nationality = ""
+		}
+	}
+}
+
+// RedactValues удаляет скрытые от роли клиента поля из снимка записи в JSON.
+func RedactValues(ctx context.Context, values json.RawMessage) json.RawMessage {
+	p, ok := PrincipalFrom(ctx)
+	if !ok || len(p.HiddenFields) == 0 || len(values) == 0 {
+		return values
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(values, &m); err != nil {
+		return values
+	}
+	for f := range p.HiddenFields {
+		delete(m, f)
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return values
+	}
+	return data
+}
@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/zatrasz75/Service/pkg/storage"
+)
+
+var (
+	// ErrInvalidTenant некорректный идентификатор арендатора.
+	ErrInvalidTenant = errors.New("некорректный идентификатор арендатора")
+	// ErrTenantMismatch запрошенный арендатор не совпадает с арендатором учётных данных.
+	ErrTenantMismatch = errors.New("арендатор не совпадает с арендатором учётных данных")
+)
+
+// Tenant определяет арендатора запроса. Аутентифицированный клиент всегда работает с арендатором
+// ключа доступа или токена (без привязки — storage.DefaultTenant), requested (заголовок X-Tenant-ID
+// или метаданные x-tenant-id) должен совпадать с ним. Без аутентификации используется requested
+// или storage.DefaultTenant.
+func Tenant(ctx context.Context, requested string) (string, error) {
+	if requested != "" && !storage.ValidTenant(requested) {
+		return "", ErrInvalidTenant
+	}
+
+	if p, ok := PrincipalFrom(ctx); ok {
+		tenant := p.Tenant
+		if tenant == "" {
+			tenant = storage.DefaultTenant
+		}
+		if requested != "" && requested != tenant {
+			return "", ErrTenantMismatch
+		}
+		return tenant, nil
+	}
+	if requested != "" {
+		return requested, nil
+	}
+	return storage.DefaultTenant, nil
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/zatrasz75/Service/configs"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"testing"
+)
+
+func TestTenant(t *testing.T) {
+	anonymous := context.Background()
+	acme := WithPrincipal(context.Background(), Principal{Subject: "billing", Role: RoleAdmin, Tenant: "acme"})
+	unbound := WithPrincipal(context.Background(), Principal{Subject: "legacy", Role: RoleAdmin})
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		requested string
+		want      string
+		wantErr   error
+	}{
+		{"без аутентификации", anonymous, "", storage.DefaultTenant, nil},
+		{"без аутентификации с заголовком", anonymous, "acme", "acme", nil},
+		{"некорректный заголовок", anonymous, "acme corp", "", ErrInvalidTenant},
+		{"все арендаторы недоступны клиенту", anonymous, storage.AllTenants, "", ErrInvalidTenant},
+		{"арендатор ключа", acme, "", "acme", nil},
+		{"заголовок совпадает", acme, "acme", "acme", nil},
+		{"чужой арендатор", acme, "globex", "", ErrTenantMismatch},
+		{"чужой арендатор по умолчанию", acme, storage.DefaultTenant, "", ErrTenantMismatch},
+		{"без привязки", unbound, "", storage.DefaultTenant, nil},
+		{"без привязки с заголовком по умолчанию", unbound, storage.DefaultTenant, storage.DefaultTenant, nil},
+		{"без привязки с чужим заголовком", unbound, "acme", "", ErrTenantMismatch},
+		{"без привязки со всеми арендаторами", unbound, storage.AllTenants, "", ErrInvalidTenant},
+	}
+	for _, tt := range tests {
+		got, err := Tenant(tt.ctx, tt.requested)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("%s: получено %q, %v, ожидалось %q, %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// TestTokenWithoutTenant проверяет, что токен без claim арендатора не позволяет выбрать
+// арендатора заголовком X-Tenant-ID.
+func TestTokenWithoutTenant(t *testing.T) {
+	k := newTestKey(t)
+	a, err := New(configs.Auth{Enabled: true, JWKSFile: k.jwks, JWTTenantClaim: "org"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := a.Authenticate(context.Background(), k.token(t, jwt.MapClaims{"sub": "user-1", "roles": "admin", "tenant_id": "acme"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Tenant != storage.DefaultTenant {
+		t.Fatalf("арендатор %q, ожидался %q: учитывается только claim org", p.Tenant, storage.DefaultTenant)
+	}
+	ctx := WithPrincipal(context.Background(), p)
+	if _, err = Tenant(ctx, "acme"); !errors.Is(err, ErrTenantMismatch) {
+		t.Errorf("заголовок чужого арендатора: ошибка %v, ожидалась %v", err, ErrTenantMismatch)
+	}
+
+	p, err = a.Authenticate(context.Background(), k.token(t, jwt.MapClaims{"sub": "user-1", "roles": "admin", "org": "acme"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tenant, err := Tenant(WithPrincipal(context.Background(), p), ""); err != nil || tenant != "acme" {
+		t.Errorf("арендатор claim org: %q, %v", tenant, err)
+	}
+}
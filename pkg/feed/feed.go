@@ -0,0 +1,191 @@
+// Package feed поток изменений записей для подписчиков gRPC, SSE и WebSocket.
+// Изменения читаются из истории изменений, поэтому подписчик может продолжить поток
+// с идентификатора последнего полученного события.
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/zatrasz75/Service/pkg/logger"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"sync"
+	"time"
+)
+
+// Значения по умолчанию.
+const (
+	defaultInterval = 5 * time.Second
+	batchSize       = 500
+)
+
+// Event Изменение записи.
+type Event struct {
+	ID        int64             `json:"id"`
+	Type      string            `json:"type"`
+	TenantID  string            `json:"tenant_id"`
+	Operation string            `json:"operation"`
+	Person    storage.UsersData `json:"person"`
+	Source    string            `json:"source"`
+	Actor     string            `json:"actor"`
+	ChangedAt time.Time         `json:"changed_at"`
+}
+
+// Hub Рассылает подписчикам уведомления о новых записях истории изменений.
+type Hub struct {
+	db       storage.Database
+	interval time.Duration // период опроса истории, если уведомление не получено
+
+	mu   sync.Mutex
+	subs map[chan struct{}]struct{}
+}
+
+// NewHub создаёт Hub. interval задаёт период опроса истории на случай потери соединения,
+// по которому приходят уведомления.
+func NewHub(db storage.Database, interval time.Duration) *Hub {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return &Hub{db: db, interval: interval, subs: make(map[chan struct{}]struct{})}
+}
+
+// Run получает уведомления об изменениях из базы данных до отмены ctx.
+// После ошибки соединения подписка возобновляется через interval.
+func (h *Hub) Run(ctx context.Context) {
+	for {
+		err := h.db.ListenChanges(ctx, h.broadcast)
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Error("Ошибка подписки на изменения записей", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(h.interval):
+		}
+	}
+}
+
+// broadcast будит всех подписчиков.
+func (h *Hub) broadcast() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subs {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (h *Hub) subscribe() chan struct{} {
+	ch := make(chan struct{}, 1)
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *Hub) unsubscribe(ch chan struct{}) {
+	h.mu.Lock()
+	delete(h.subs, ch)
+	h.mu.Unlock()
+}
+
+// Watch передаёт в fn изменения записей арендатора из ctx, подходящие под filter, следующие
+// за событием afterID, до отмены ctx или ошибки fn. События передаются в порядке фиксации
+// транзакций, поэтому их идентификаторы могут идти не по возрастанию.
+// При afterID <= 0 передаются только новые изменения.
+func (h *Hub) Watch(ctx context.Context, afterID int64, filter storage.Filter, fn func(Event) error) error {
+	sub := h.subscribe()
+	defer h.unsubscribe(sub)
+
+	if afterID <= 0 {
+		last, err := h.db.LastChangeID(ctx)
+		if err != nil {
+			return err
+		}
+		afterID = last
+	}
+
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		entries, err := h.db.Changes(ctx, afterID, batchSize)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		for _, e := range entries {
+			afterID = e.ID
+			event, err := toEvent(e)
+			if err != nil {
+				logger.Error("Некорректная запись истории изменений", err)
+				continue
+			}
+			if !match(event, filter) {
+				continue
+			}
+			if err = fn(event); err != nil {
+				return err
+			}
+		}
+		if len(entries) == batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sub:
+		case <-ticker.C:
+		}
+	}
+}
+
+// match сообщает, подходит ли событие под фильтр GET /data. Удалённые записи передаются
+// только с IncludeDeleted, кроме самого события удаления.
+func match(e Event, filter storage.Filter) bool {
+	if filter.Gender != "" && e.Person.Gender != filter.Gender {
+		return false
+	}
+	if !filter.IncludeDeleted && e.Person.DeletedAt != nil && e.Operation != storage.OpDelete {
+		return false
+	}
+	return true
+}
+
+// toEvent преобразует запись истории изменений в событие. Состояние записи берётся
+// после изменения, а для окончательного удаления — до него.
+func toEvent(e storage.HistoryEntry) (Event, error) {
+	event := Event{
+		ID:        e.ID,
+		Type:      storage.EventTypes[e.Operation],
+		TenantID:  e.TenantID,
+		Operation: e.Operation,
+		Source:    e.Source,
+		Actor:     e.Actor,
+		ChangedAt: e.ChangedAt,
+	}
+	if e.Operation == storage.OpPurge {
+		event.Type = storage.EventPersonPurged
+	}
+
+	values := e.NewValues
+	if len(values) == 0 {
+		values = e.OldValues
+	}
+	if len(values) > 0 {
+		if err := json.Unmarshal(values, &event.Person); err != nil {
+			return Event{}, err
+		}
+	}
+	event.Person.ID = e.PersonID
+
+	return event, nil
+}
@@ -0,0 +1,93 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"github.com/zatrasz75/Service/pkg/auth"
+	"github.com/zatrasz75/Service/pkg/grpcapi/peoplepb"
+	"github.com/zatrasz75/Service/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// methodPermissions разрешения методов сервиса, как у соответствующих маршрутов REST.
+var methodPermissions = map[string]auth.Permission{
+	peoplepb.People_GetPerson_FullMethodName:    auth.PermRead,
+	peoplepb.People_ListPeople_FullMethodName:   auth.PermRead,
+	peoplepb.People_WatchPeople_FullMethodName:  auth.PermRead,
+	peoplepb.People_CreatePerson_FullMethodName: auth.PermWrite,
+	peoplepb.People_UpdatePerson_FullMethodName: auth.PermWrite,
+	peoplepb.People_PatchPerson_FullMethodName:  auth.PermWrite,
+	peoplepb.People_DeletePerson_FullMethodName: auth.PermDelete,
+}
+
+// credentials возвращает ключ доступа из метаданных x-api-key или токен из authorization: Bearer.
+func credentials(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if key := md.Get("x-api-key"); len(key) > 0 {
+		return key[0]
+	}
+	if v := md.Get("authorization"); len(v) > 0 {
+		scheme, token, ok := strings.Cut(v[0], " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ""
+}
+
+// authenticate проверяет учётные данные и разрешение роли на вызов метода method
+// и возвращает контекст с аутентифицированным клиентом.
+func authenticate(ctx context.Context, a *auth.Authenticator, method string) (context.Context, error) {
+	p, err := a.Authenticate(ctx, credentials(ctx))
+	if errors.Is(err, auth.ErrNoCredentials) || errors.Is(err, auth.ErrInvalidCredentials) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		logger.Error("Ошибка при проверке учётных данных", err)
+		return nil, status.Error(codes.Internal, "Ошибка при проверке учётных данных")
+	}
+	perm, ok := methodPermissions[method]
+	if !ok {
+		perm = auth.PermAdmin
+	}
+	if !p.Can(perm) {
+		return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+	}
+	return auth.WithPrincipal(ctx, p), nil
+}
+
+// unaryAuth перехватчик, проверяющий учётные данные и разрешения унарных вызовов.
+func unaryAuth(a *auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticate(ctx, a, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// streamAuth перехватчик, проверяющий учётные данные и разрешения потоковых вызовов.
+func streamAuth(a *auth.Authenticator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), a, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// contextStream поток с контекстом, дополненным перехватчиком.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
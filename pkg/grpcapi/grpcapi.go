@@ -0,0 +1,95 @@
+// Package grpcapi сервер gRPC, работающий параллельно с REST API.
+// Обработчики используют ту же реализацию storage.Database, что и REST.
+package grpcapi
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative peoplepb/people.proto
+
+import (
+	"context"
+	"github.com/zatrasz75/Service/configs"
+	"github.com/zatrasz75/Service/pkg/auth"
+	"github.com/zatrasz75/Service/pkg/feed"
+	"github.com/zatrasz75/Service/pkg/grpcapi/peoplepb"
+	"github.com/zatrasz75/Service/pkg/logger"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"google.golang.org/grpc"
+	"net"
+	"time"
+)
+
+// API сервер gRPC.
+type API struct {
+	host string // Хост
+	port string // Порт
+	srv  *grpc.Server
+
+	shutdownTime time.Duration
+}
+
+// New создаёт сервер gRPC с общим подключением к базе данных и потоком изменений.
+// Если аутентификация включена, вызовы проверяются так же, как запросы REST.
+// Вызовы работают с данными арендатора из учётных данных или метаданных x-tenant-id.
+func New(cfg *configs.Config, PG storage.Database, hub *feed.Hub, authn *auth.Authenticator) *API {
+	var unary []grpc.UnaryServerInterceptor
+	var stream []grpc.StreamServerInterceptor
+	if authn != nil && authn.Enabled() {
+		unary = append(unary, unaryAuth(authn))
+		stream = append(stream, streamAuth(authn))
+	}
+	unary = append(unary, unaryTenant)
+	stream = append(stream, streamTenant)
+	opts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...)}
+
+	api := &API{
+		host:         cfg.Server.AddrHost,
+		port:         cfg.GRPC.AddrPort,
+		srv:          grpc.NewServer(opts...),
+		shutdownTime: cfg.Server.ShutdownTime,
+	}
+	peoplepb.RegisterPeopleServer(api.srv, &Server{
+		PG:             PG,
+		RequireVersion: cfg.Server.RequireIfMatch,
+		Feed:           hub,
+	})
+
+	return api
+}
+
+// Run Метод для запуска сервера
+func (api *API) Run() error {
+	addr := api.host + ":" + api.port
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	logger.Info("Запуск сервера gRPC на " + addr)
+
+	go func() {
+		err := api.srv.Serve(lis)
+		if err != nil {
+			logger.Error("Остановка сервера gRPC", err)
+		}
+	}()
+
+	return nil
+}
+
+// Stop Метод для остановки сервера. Открытые потоки WatchPeople
+// прерываются, если они не завершились за время ShutdownTime.
+func (api *API) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), api.shutdownTime)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		api.srv.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		api.srv.Stop()
+	}
+	logger.Info("Сервер gRPC успешно выключен")
+}
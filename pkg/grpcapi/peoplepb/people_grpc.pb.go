@@ -0,0 +1,365 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.3
+// source: people.proto
+
+package peoplepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	People_GetPerson_FullMethodName    = "/people.v1.People/GetPerson"
+	People_ListPeople_FullMethodName   = "/people.v1.People/ListPeople"
+	People_CreatePerson_FullMethodName = "/people.v1.People/CreatePerson"
+	People_UpdatePerson_FullMethodName = "/people.v1.People/UpdatePerson"
+	People_PatchPerson_FullMethodName  = "/people.v1.People/PatchPerson"
+	People_DeletePerson_FullMethodName = "/people.v1.People/DeletePerson"
+	People_WatchPeople_FullMethodName  = "/people.v1.People/WatchPeople"
+)
+
+// PeopleClient is the client API for People service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PeopleClient interface {
+	GetPerson(ctx context.Context, in *GetPersonRequest, opts ...grpc.CallOption) (*Person, error)
+	ListPeople(ctx context.Context, in *ListPeopleRequest, opts ...grpc.CallOption) (*ListPeopleResponse, error)
+	CreatePerson(ctx context.Context, in *CreatePersonRequest, opts ...grpc.CallOption) (*CreatePersonResponse, error)
+	// UpdatePerson заменяет все изменяемые поля записи.
+	UpdatePerson(ctx context.Context, in *UpdatePersonRequest, opts ...grpc.CallOption) (*VersionResponse, error)
+	// PatchPerson изменяет только переданные поля (аналог JSON Merge Patch).
+	PatchPerson(ctx context.Context, in *PatchPersonRequest, opts ...grpc.CallOption) (*VersionResponse, error)
+	DeletePerson(ctx context.Context, in *DeletePersonRequest, opts ...grpc.CallOption) (*DeletePersonResponse, error)
+	// WatchPeople передаёт изменения записей по мере их появления в истории.
+	WatchPeople(ctx context.Context, in *WatchPeopleRequest, opts ...grpc.CallOption) (People_WatchPeopleClient, error)
+}
+
+type peopleClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPeopleClient(cc grpc.ClientConnInterface) PeopleClient {
+	return &peopleClient{cc}
+}
+
+func (c *peopleClient) GetPerson(ctx context.Context, in *GetPersonRequest, opts ...grpc.CallOption) (*Person, error) {
+	out := new(Person)
+	err := c.cc.Invoke(ctx, People_GetPerson_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peopleClient) ListPeople(ctx context.Context, in *ListPeopleRequest, opts ...grpc.CallOption) (*ListPeopleResponse, error) {
+	out := new(ListPeopleResponse)
+	err := c.cc.Invoke(ctx, People_ListPeople_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peopleClient) CreatePerson(ctx context.Context, in *CreatePersonRequest, opts ...grpc.CallOption) (*CreatePersonResponse, error) {
+	out := new(CreatePersonResponse)
+	err := c.cc.Invoke(ctx, People_CreatePerson_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peopleClient) UpdatePerson(ctx context.Context, in *UpdatePersonRequest, opts ...grpc.CallOption) (*VersionResponse, error) {
+	out := new(VersionResponse)
+	err := c.cc.Invoke(ctx, People_UpdatePerson_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peopleClient) PatchPerson(ctx context.Context, in *PatchPersonRequest, opts ...grpc.CallOption) (*VersionResponse, error) {
+	out := new(VersionResponse)
+	err := c.cc.Invoke(ctx, People_PatchPerson_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peopleClient) DeletePerson(ctx context.Context, in *DeletePersonRequest, opts ...grpc.CallOption) (*DeletePersonResponse, error) {
+	out := new(DeletePersonResponse)
+	err := c.cc.Invoke(ctx, People_DeletePerson_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peopleClient) WatchPeople(ctx context.Context, in *WatchPeopleRequest, opts ...grpc.CallOption) (People_WatchPeopleClient, error) {
+	stream, err := c.cc.NewStream(ctx, &People_ServiceDesc.Streams[0], People_WatchPeople_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &peopleWatchPeopleClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type People_WatchPeopleClient interface {
+	Recv() (*PersonEvent, error)
+	grpc.ClientStream
+}
+
+type peopleWatchPeopleClient struct {
+	grpc.ClientStream
+}
+
+func (x *peopleWatchPeopleClient) Recv() (*PersonEvent, error) {
+	m := new(PersonEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// PeopleServer is the server API for People service.
+// All implementations must embed UnimplementedPeopleServer
+// for forward compatibility
+type PeopleServer interface {
+	GetPerson(context.Context, *GetPersonRequest) (*Person, error)
+	ListPeople(context.Context, *ListPeopleRequest) (*ListPeopleResponse, error)
+	CreatePerson(context.Context, *CreatePersonRequest) (*CreatePersonResponse, error)
+	// UpdatePerson заменяет все изменяемые поля записи.
+	UpdatePerson(context.Context, *UpdatePersonRequest) (*VersionResponse, error)
+	// PatchPerson изменяет только переданные поля (аналог JSON Merge Patch).
+	PatchPerson(context.Context, *PatchPersonRequest) (*VersionResponse, error)
+	DeletePerson(context.Context, *DeletePersonRequest) (*DeletePersonResponse, error)
+	// WatchPeople передаёт изменения записей по мере их появления в истории.
+	WatchPeople(*WatchPeopleRequest, People_WatchPeopleServer) error
+	mustEmbedUnimplementedPeopleServer()
+}
+
+// UnimplementedPeopleServer must be embedded to have forward compatible implementations.
+type UnimplementedPeopleServer struct {
+}
+
+func (UnimplementedPeopleServer) GetPerson(context.Context, *GetPersonRequest) (*Person, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPerson not implemented")
+}
+func (UnimplementedPeopleServer) ListPeople(context.Context, *ListPeopleRequest) (*ListPeopleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPeople not implemented")
+}
+func (UnimplementedPeopleServer) CreatePerson(context.Context, *CreatePersonRequest) (*CreatePersonResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePerson not implemented")
+}
+func (UnimplementedPeopleServer) UpdatePerson(context.Context, *UpdatePersonRequest) (*VersionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdatePerson not implemented")
+}
+func (UnimplementedPeopleServer) PatchPerson(context.Context, *PatchPersonRequest) (*VersionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PatchPerson not implemented")
+}
+func (UnimplementedPeopleServer) DeletePerson(context.Context, *DeletePersonRequest) (*DeletePersonResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeletePerson not implemented")
+}
+func (UnimplementedPeopleServer) WatchPeople(*WatchPeopleRequest, People_WatchPeopleServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchPeople not implemented")
+}
+func (UnimplementedPeopleServer) mustEmbedUnimplementedPeopleServer() {}
+
+// UnsafePeopleServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PeopleServer will
+// result in compilation errors.
+type UnsafePeopleServer interface {
+	mustEmbedUnimplementedPeopleServer()
+}
+
+func RegisterPeopleServer(s grpc.ServiceRegistrar, srv PeopleServer) {
+	s.RegisterService(&People_ServiceDesc, srv)
+}
+
+func _People_GetPerson_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPersonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeopleServer).GetPerson(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: People_GetPerson_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeopleServer).GetPerson(ctx, req.(*GetPersonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _People_ListPeople_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPeopleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeopleServer).ListPeople(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: People_ListPeople_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeopleServer).ListPeople(ctx, req.(*ListPeopleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _People_CreatePerson_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreatePersonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeopleServer).CreatePerson(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: People_CreatePerson_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeopleServer).CreatePerson(ctx, req.(*CreatePersonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _People_UpdatePerson_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdatePersonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeopleServer).UpdatePerson(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: People_UpdatePerson_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeopleServer).UpdatePerson(ctx, req.(*UpdatePersonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _People_PatchPerson_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PatchPersonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeopleServer).PatchPerson(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: People_PatchPerson_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeopleServer).PatchPerson(ctx, req.(*PatchPersonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _People_DeletePerson_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeletePersonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeopleServer).DeletePerson(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: People_DeletePerson_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeopleServer).DeletePerson(ctx, req.(*DeletePersonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _People_WatchPeople_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchPeopleRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PeopleServer).WatchPeople(m, &peopleWatchPeopleServer{stream})
+}
+
+type People_WatchPeopleServer interface {
+	Send(*PersonEvent) error
+	grpc.ServerStream
+}
+
+type peopleWatchPeopleServer struct {
+	grpc.ServerStream
+}
+
+func (x *peopleWatchPeopleServer) Send(m *PersonEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// People_ServiceDesc is the grpc.ServiceDesc for People service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var People_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "people.v1.People",
+	HandlerType: (*PeopleServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetPerson",
+			Handler:    _People_GetPerson_Handler,
+		},
+		{
+			MethodName: "ListPeople",
+			Handler:    _People_ListPeople_Handler,
+		},
+		{
+			MethodName: "CreatePerson",
+			Handler:    _People_CreatePerson_Handler,
+		},
+		{
+			MethodName: "UpdatePerson",
+			Handler:    _People_UpdatePerson_Handler,
+		},
+		{
+			MethodName: "PatchPerson",
+			Handler:    _People_PatchPerson_Handler,
+		},
+		{
+			MethodName: "DeletePerson",
+			Handler:    _People_DeletePerson_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchPeople",
+			Handler:       _People_WatchPeople_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "people.proto",
+}
@@ -0,0 +1,265 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"github.com/zatrasz75/Service/pkg/auth"
+	"github.com/zatrasz75/Service/pkg/feed"
+	"github.com/zatrasz75/Service/pkg/grpcapi/peoplepb"
+	"github.com/zatrasz75/Service/pkg/logger"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server Реализация сервиса peoplepb.PeopleServer.
+type Server struct {
+	peoplepb.UnimplementedPeopleServer
+
+	PG storage.Database
+
+	// RequireVersion требует ожидаемую версию записи в UpdatePerson и PatchPerson.
+	RequireVersion bool
+	// Feed поток изменений записей для WatchPeople.
+	Feed *feed.Hub
+}
+
+// auditContext возвращает контекст с информацией об источнике и авторе изменений.
+// Автором считается аутентифицированный клиент, без аутентификации — значение метаданных x-actor.
+func auditContext(ctx context.Context) context.Context {
+	a := storage.Audit{Source: storage.SourceGRPC}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if actor := md.Get("x-actor"); len(actor) > 0 {
+			a.Actor = actor[0]
+		}
+	}
+	if p, ok := auth.PrincipalFrom(ctx); ok {
+		a.Actor = p.Subject
+		a.Role = p.Role
+	}
+	return storage.WithAudit(ctx, a)
+}
+
+// statusError преобразует ошибку хранилища в статус gRPC.
+// Внутренние ошибки записываются в лог и возвращаются без подробностей.
+func statusError(err error, msg string) error {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return status.Error(codes.NotFound, "Запись не найдена")
+	case errors.Is(err, storage.ErrVersionConflict):
+		return status.Error(codes.Aborted, "Запись была изменена другим запросом")
+	case errors.Is(err, storage.ErrInvalidPatch):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		logger.Error(msg, err)
+		return status.Error(codes.Internal, msg)
+	}
+}
+
+// checkID проверяет идентификатор записи.
+func checkID(id int32) error {
+	if id <= 0 {
+		return status.Error(codes.InvalidArgument, "некорректный идентификатор")
+	}
+	return nil
+}
+
+// checkVersion проверяет наличие ожидаемой версии, если она обязательна.
+func (s *Server) checkVersion(version int32) error {
+	if version < 0 {
+		return status.Error(codes.InvalidArgument, "некорректная версия")
+	}
+	if version == 0 && s.RequireVersion {
+		return status.Error(codes.FailedPrecondition, "требуется версия записи")
+	}
+	return nil
+}
+
+// toPerson преобразует запись хранилища в сообщение Person.
+func toPerson(d storage.UsersData) *peoplepb.Person {
+	p := &peoplepb.Person{
+		Id:          int32(d.ID),
+		Name:        d.Name,
+		Surname:     d.Surname,
+		Patronymic:  d.Patronymic,
+		Age:         int32(d.Age),
+		Gender:      d.Gender,
+		Nationality: d.Nationality,
+		Version:     int32(d.Version),
+		CreatedAt:   timestamppb.New(d.CreatedAt),
+		UpdatedAt:   timestamppb.New(d.UpdatedAt),
+	}
+	if d.DeletedAt != nil {
+		p.DeletedAt = timestamppb.New(*d.DeletedAt)
+	}
+	return p
+}
+
+// GetPerson возвращает запись по идентификатору.
+func (s *Server) GetPerson(ctx context.Context, req *peoplepb.GetPersonRequest) (*peoplepb.Person, error) {
+	if err := checkID(req.GetId()); err != nil {
+		return nil, err
+	}
+
+	data, err := s.PG.GetDataByID(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, statusError(err, "Ошибка при выполнении запроса к базе данных")
+	}
+	auth.Redact(ctx, &data)
+
+	return toPerson(data), nil
+}
+
+// ListPeople возвращает страницу записей с фильтрами, как GET /data.
+func (s *Server) ListPeople(ctx context.Context, req *peoplepb.ListPeopleRequest) (*peoplepb.ListPeopleResponse, error) {
+	page, pageSize := int(req.GetPage()), int(req.GetPageSize())
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 1
+	}
+	// Недоступные клиенту фильтры игнорируются, как в GET /data.
+	filter := auth.RestrictFilter(ctx, storage.Filter{Gender: req.GetGender(), IncludeDeleted: req.GetIncludeDeleted()})
+
+	data, err := s.PG.Select(ctx, filter, page, pageSize)
+	if err != nil {
+		return nil, statusError(err, "Ошибка при выполнении запроса к базе данных")
+	}
+
+	resp := &peoplepb.ListPeopleResponse{People: make([]*peoplepb.Person, len(data))}
+	for i, d := range data {
+		auth.Redact(ctx, &d)
+		resp.People[i] = toPerson(d)
+	}
+	return resp, nil
+}
+
+// CreatePerson добавляет новую запись.
+func (s *Server) CreatePerson(ctx context.Context, req *peoplepb.CreatePersonRequest) (*peoplepb.CreatePersonResponse, error) {
+	if req.GetAge() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "возраст должен быть неотрицательным")
+	}
+
+	id, err := s.PG.SaveDataToDatabase(auditContext(ctx), storage.Data{
+		Name:        req.GetName(),
+		Surname:     req.GetSurname(),
+		Patronymic:  req.GetPatronymic(),
+		Age:         int(req.GetAge()),
+		Gender:      req.GetGender(),
+		Nationality: req.GetNationality(),
+	})
+	if err != nil {
+		return nil, statusError(err, "Ошибка при сохранении данных в базу данных")
+	}
+
+	return &peoplepb.CreatePersonResponse{Id: int32(id)}, nil
+}
+
+// UpdatePerson заменяет изменяемые поля записи.
+func (s *Server) UpdatePerson(ctx context.Context, req *peoplepb.UpdatePersonRequest) (*peoplepb.VersionResponse, error) {
+	if err := checkID(req.GetId()); err != nil {
+		return nil, err
+	}
+	if err := s.checkVersion(req.GetVersion()); err != nil {
+		return nil, err
+	}
+	if req.GetAge() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "возраст должен быть неотрицательным")
+	}
+
+	version, err := s.PG.UpdateDataByID(auditContext(ctx), int(req.GetId()), storage.UsersData{
+		Name:        req.GetName(),
+		Surname:     req.GetSurname(),
+		Patronymic:  req.GetPatronymic(),
+		Age:         int(req.GetAge()),
+		Gender:      req.GetGender(),
+		Nationality: req.GetNationality(),
+	}, int(req.GetVersion()))
+	if err != nil {
+		return nil, statusError(err, "Ошибка при обновлении данных")
+	}
+
+	return &peoplepb.VersionResponse{Version: int32(version)}, nil
+}
+
+// PatchPerson изменяет переданные поля записи. Поля проверяются по схеме storage.PatchableFields,
+// как в PATCH /data/{id}.
+func (s *Server) PatchPerson(ctx context.Context, req *peoplepb.PatchPersonRequest) (*peoplepb.VersionResponse, error) {
+	if err := checkID(req.GetId()); err != nil {
+		return nil, err
+	}
+	if err := s.checkVersion(req.GetVersion()); err != nil {
+		return nil, err
+	}
+
+	fields := req.GetPatch().AsMap()
+	if len(fields) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "нет полей для обновления")
+	}
+	partialData := make(map[string]interface{}, len(fields))
+	for field, value := range fields {
+		if auth.Hidden(ctx, field) {
+			return nil, status.Errorf(codes.PermissionDenied, "поле %s скрыто от роли клиента", field)
+		}
+		v, err := storage.NormalizeField(field, value)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		partialData[field] = v
+	}
+
+	version, err := s.PG.PartialUpdateDataByID(auditContext(ctx), int(req.GetId()), partialData, int(req.GetVersion()))
+	if err != nil {
+		return nil, statusError(err, "Ошибка при частичном обновлении данных")
+	}
+
+	return &peoplepb.VersionResponse{Version: int32(version)}, nil
+}
+
+// DeletePerson помечает запись удалённой.
+func (s *Server) DeletePerson(ctx context.Context, req *peoplepb.DeletePersonRequest) (*peoplepb.DeletePersonResponse, error) {
+	if err := checkID(req.GetId()); err != nil {
+		return nil, err
+	}
+
+	err := s.PG.DeleteDataByID(auditContext(ctx), int(req.GetId()))
+	if err != nil {
+		return nil, statusError(err, "Ошибка при удалении данных")
+	}
+
+	return &peoplepb.DeletePersonResponse{}, nil
+}
+
+// WatchPeople передаёт изменения записей из истории изменений. Клиент может продолжить
+// поток после разрыва, передав идентификатор последнего полученного события в after_id.
+func (s *Server) WatchPeople(req *peoplepb.WatchPeopleRequest, stream peoplepb.People_WatchPeopleServer) error {
+	filter := auth.RestrictFilter(stream.Context(), storage.Filter{Gender: req.GetGender(), IncludeDeleted: req.GetIncludeDeleted()})
+
+	err := s.Feed.Watch(stream.Context(), req.GetAfterId(), filter, func(e feed.Event) error {
+		auth.Redact(stream.Context(), &e.Person)
+		return stream.Send(toEvent(e))
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return statusError(err, "Ошибка при чтении истории изменений")
+	}
+
+	return nil
+}
+
+// toEvent преобразует событие потока изменений в сообщение PersonEvent.
+func toEvent(e feed.Event) *peoplepb.PersonEvent {
+	return &peoplepb.PersonEvent{
+		Id:        e.ID,
+		Operation: e.Operation,
+		Person:    toPerson(e.Person),
+		Source:    e.Source,
+		Actor:     e.Actor,
+		ChangedAt: timestamppb.New(e.ChangedAt),
+	}
+}
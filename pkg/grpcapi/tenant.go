@@ -0,0 +1,49 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"github.com/zatrasz75/Service/pkg/auth"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// tenantContext возвращает контекст с арендатором из учётных данных или метаданных x-tenant-id.
+func tenantContext(ctx context.Context) (context.Context, error) {
+	var requested string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("x-tenant-id"); len(v) > 0 {
+			requested = v[0]
+		}
+	}
+
+	tenant, err := auth.Tenant(ctx, requested)
+	if errors.Is(err, auth.ErrTenantMismatch) {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return storage.WithTenant(ctx, tenant), nil
+}
+
+// unaryTenant перехватчик, передающий арендатора унарным вызовам.
+func unaryTenant(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := tenantContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// streamTenant перехватчик, передающий арендатора потоковым вызовам.
+func streamTenant(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := tenantContext(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/zatrasz75/Service/pkg/auth"
+	"github.com/zatrasz75/Service/pkg/logger"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// apiKeyRequest Тело запроса создания ключа доступа.
+type apiKeyRequest struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// CreateAPIKey Метод для обработки POST-запроса на эндпоинт /admin/api-keys.
+// Ключ возвращается только в этом ответе, в базе данных хранится его хеш.
+// Если роль не передана, ключ получает роль reader.
+func (s *Server) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req apiKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeReadError(w, err, "Ошибка при чтении JSON")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "name не может быть пустым", http.StatusUnprocessableEntity)
+		return
+	}
+	if req.Role == "" {
+		req.Role = auth.RoleReader
+	}
+	if !auth.ValidRole(req.Role) {
+		http.Error(w, "role должна быть reader, editor или admin", http.StatusUnprocessableEntity)
+		return
+	}
+
+	key, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		logger.Error("Не удалось сгенерировать ключ доступа", err)
+		http.Error(w, "Не удалось сгенерировать ключ доступа", http.StatusInternalServerError)
+		return
+	}
+	apiKey, err := s.PG.CreateAPIKey(r.Context(), req.Name, req.Role, prefix, hash)
+	if err != nil {
+		logger.Error("Ошибка при создании ключа доступа", err)
+		http.Error(w, "Ошибка при создании ключа доступа", http.StatusInternalServerError)
+		return
+	}
+	apiKey.Key = key
+
+	w.Header().Set("Location", "/api/v1/admin/api-keys/"+strconv.FormatInt(apiKey.ID, 10))
+	writeJSON(w, http.StatusCreated, apiKey)
+}
+
+// ListAPIKeys Метод для обработки GET-запроса на эндпоинт /admin/api-keys.
+func (s *Server) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := s.PG.ListAPIKeys(r.Context())
+	if err != nil {
+		logger.Error("Ошибка при получении ключей доступа", err)
+		http.Error(w, "Ошибка при получении ключей доступа", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, keys)
+}
+
+// RevokeAPIKey Метод для обработки DELETE-запроса на эндпоинт /admin/api-keys/{id}.
+// Ключ остаётся в списке с отметкой revoked_at.
+func (s *Server) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	err := s.PG.RevokeAPIKey(r.Context(), pathID(r, "id"))
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Ключ доступа не найден", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Error("Ошибка при отзыве ключа доступа", err)
+		http.Error(w, "Ошибка при отзыве ключа доступа", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "Ключ доступа отозван"})
+}
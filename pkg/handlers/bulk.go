@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/zatrasz75/Service/pkg/logger"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// defaultBulkMaxItems максимальный размер пакета, если он не задан в конфигурации.
+const defaultBulkMaxItems = 1000
+
+// Типы содержимого потока NDJSON.
+const (
+	contentTypeNDJSON  = "application/x-ndjson"
+	contentTypeNDJSON2 = "application/ndjson"
+)
+
+var (
+	// errBatchTooLarge пакет превышает допустимый размер.
+	errBatchTooLarge = errors.New("превышен максимальный размер пакета")
+	// errEmptyBatch пакет не содержит элементов.
+	errEmptyBatch = errors.New("пакет не содержит элементов")
+)
+
+// bulkItemResult Результат обработки элемента пакета в ответе.
+type bulkItemResult struct {
+	Index   int    `json:"index"`
+	ID      int    `json:"id,omitempty"`
+	Version int    `json:"version,omitempty"`
+	Status  int    `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+// bulkPatchItem Элемент запроса PATCH /data/bulk.
+type bulkPatchItem struct {
+	ID      int             `json:"id"`
+	Version int             `json:"version"`
+	Patch   json.RawMessage `json:"patch"`
+}
+
+// maxBulkItems возвращает максимальный размер пакета.
+func (s *Server) maxBulkItems() int {
+	if s.BulkMaxItems > 0 {
+		return s.BulkMaxItems
+	}
+	return defaultBulkMaxItems
+}
+
+// isNDJSON сообщает, передано ли тело запроса в формате NDJSON.
+func isNDJSON(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == contentTypeNDJSON || mediaType == contentTypeNDJSON2
+}
+
+// decodeItems читает элементы пакета из JSON-массива или потока NDJSON
+// и передаёт каждый из них в fn. Читает не более max элементов.
+func decodeItems(r *http.Request, max int, fn func(raw json.RawMessage) error) error {
+	count := 0
+	err := readItems(r, func(raw json.RawMessage) error {
+		count++
+		if count > max {
+			return fmt.Errorf("%w: %d", errBatchTooLarge, max)
+		}
+		return fn(raw)
+	})
+	if err == nil && count == 0 {
+		return errEmptyBatch
+	}
+
+	return err
+}
+
+// readItems читает элементы из JSON-массива или потока NDJSON.
+func readItems(r *http.Request, next func(raw json.RawMessage) error) error {
+	if isNDJSON(r) {
+		scanner := bufio.NewScanner(r.Body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			if err := next(append(json.RawMessage(nil), line...)); err != nil {
+				return err
+			}
+		}
+		return scanner.Err()
+	}
+
+	dec := json.NewDecoder(r.Body)
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return errors.New("ожидается JSON-массив")
+	}
+	for dec.More() {
+		var raw json.RawMessage
+		if err = dec.Decode(&raw); err != nil {
+			return err
+		}
+		if err = next(raw); err != nil {
+			return err
+		}
+	}
+	_, err = dec.Token()
+	if err == io.EOF {
+		return nil
+	}
+
+	return err
+}
+
+// isAtomic сообщает, нужно ли выполнять пакет в одной транзакции (mode=atomic, по умолчанию)
+// или обрабатывать элементы независимо (mode=items).
+func isAtomic(r *http.Request) bool {
+	return r.URL.Query().Get("mode") != "items"
+}
+
+// statusForError возвращает HTTP-статус для ошибки хранилища.
+func statusForError(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrVersionConflict):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, storage.ErrInvalidPatch):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeBulkDecodeError отправляет ответ на ошибку чтения пакета.
+func writeBulkDecodeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errBatchTooLarge) {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, "Тело запроса превышает допустимый размер", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if errors.Is(err, storage.ErrInvalidPatch) || errors.Is(err, errMalformedPatch) || errors.Is(err, errHiddenField) {
+		writePatchError(w, err)
+		return
+	}
+	http.Error(w, "Ошибка при чтении пакета: "+err.Error(), http.StatusBadRequest)
+}
+
+// writeBulkResult отправляет результат пакетной операции.
+// Ошибка атомарного пакета возвращается статусом элемента, вызвавшего откат.
+func writeBulkResult(w http.ResponseWriter, results []storage.BatchResult, err error, okStatus int) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var batchErr *storage.BatchError
+	if errors.As(err, &batchErr) {
+		status := statusForError(batchErr.Err)
+		if status == http.StatusInternalServerError {
+			logger.Error("Ошибка при выполнении пакетной операции", err)
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": batchErr.Err.Error(),
+			"index": batchErr.Index,
+		})
+		return
+	}
+	if err != nil {
+		logger.Error("Ошибка при выполнении пакетной операции", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Ошибка при выполнении пакетной операции"})
+		return
+	}
+
+	items := make([]bulkItemResult, len(results))
+	failed := false
+	for i, res := range results {
+		items[i] = bulkItemResult{Index: i, ID: res.ID, Version: res.Version, Status: okStatus}
+		if res.Err != nil {
+			failed = true
+			items[i].Status = statusForError(res.Err)
+			items[i].Error = res.Err.Error()
+			if items[i].Status == http.StatusInternalServerError {
+				logger.Error("Ошибка при обработке элемента пакета", res.Err)
+			}
+		}
+	}
+
+	status := okStatus
+	if failed {
+		status = http.StatusMultiStatus
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+}
+
+// BulkAddData Метод для обработки POST-запроса на эндпоинт /data/bulk.
+func (s *Server) BulkAddData(w http.ResponseWriter, r *http.Request) {
+	var items []storage.Data
+	err := decodeItems(r, s.maxBulkItems(), func(raw json.RawMessage) error {
+		var d storage.Data
+		if err := json.Unmarshal(raw, &d); err != nil {
+			return err
+		}
+		items = append(items, d)
+		return nil
+	})
+	if err != nil {
+		writeBulkDecodeError(w, err)
+		return
+	}
+
+	results, err := s.PG.SaveDataBatch(auditContext(r), items, isAtomic(r))
+	writeBulkResult(w, results, err, http.StatusCreated)
+}
+
+// BulkPartialUpdateData Метод для обработки PATCH-запроса на эндпоинт /data/bulk.
+// Каждый элемент содержит id, необязательную версию и документ JSON Merge Patch.
+func (s *Server) BulkPartialUpdateData(w http.ResponseWriter, r *http.Request) {
+	var items []storage.PatchItem
+	err := decodeItems(r, s.maxBulkItems(), func(raw json.RawMessage) error {
+		var item bulkPatchItem
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return err
+		}
+		if item.ID <= 0 {
+			return errors.New("отсутствует идентификатор элемента")
+		}
+		data, err := mergePatch(r.Context(), item.Patch)
+		if err != nil {
+			return fmt.Errorf("элемент с id %d: %w", item.ID, err)
+		}
+		items = append(items, storage.PatchItem{ID: item.ID, Version: item.Version, Data: data})
+		return nil
+	})
+	if err != nil {
+		writeBulkDecodeError(w, err)
+		return
+	}
+
+	results, err := s.PG.PartialUpdateBatch(auditContext(r), items, isAtomic(r))
+	writeBulkResult(w, results, err, http.StatusOK)
+}
+
+// BulkDeleteData Метод для обработки DELETE-запроса на эндпоинт /data/bulk.
+// Тело запроса содержит идентификаторы удаляемых записей.
+func (s *Server) BulkDeleteData(w http.ResponseWriter, r *http.Request) {
+	var ids []int
+	err := decodeItems(r, s.maxBulkItems(), func(raw json.RawMessage) error {
+		var id int
+		if err := json.Unmarshal(raw, &id); err != nil {
+			return err
+		}
+		if id <= 0 {
+			return errors.New("некорректный идентификатор")
+		}
+		ids = append(ids, id)
+		return nil
+	})
+	if err != nil {
+		writeBulkDecodeError(w, err)
+		return
+	}
+
+	results, err := s.PG.DeleteBatch(auditContext(r), ids, isAtomic(r))
+	writeBulkResult(w, results, err, http.StatusOK)
+}
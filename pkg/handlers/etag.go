@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// errIfMatchRequired возвращается, если заголовок If-Match обязателен, но не передан.
+var errIfMatchRequired = errors.New("требуется заголовок If-Match")
+
+// etag формирует значение заголовка ETag по версии записи.
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseIfMatch возвращает ожидаемую версию записи из заголовка If-Match.
+// Ноль означает, что проверка версии не требуется.
+func (s *Server) parseIfMatch(r *http.Request) (int, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" {
+		if s.RequireIfMatch {
+			return 0, errIfMatchRequired
+		}
+		return 0, nil
+	}
+	if value == "*" {
+		return 0, nil
+	}
+
+	value = strings.TrimPrefix(value, "W/")
+	version, err := strconv.Atoi(strings.Trim(value, `"`))
+	if err != nil || version <= 0 {
+		return 0, errors.New("некорректный заголовок If-Match")
+	}
+
+	return version, nil
+}
+
+// writeIfMatchError отправляет ответ на ошибку разбора If-Match.
+func writeIfMatchError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errIfMatchRequired) {
+		http.Error(w, err.Error(), http.StatusPreconditionRequired)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		required bool
+		want     int
+		wantErr  bool
+	}{
+		{name: "без заголовка", header: "", want: 0},
+		{name: "без обязательного заголовка", header: "", required: true, wantErr: true},
+		{name: "версия", header: `"3"`, want: 3},
+		{name: "пробелы", header: ` "3" `, want: 3},
+		{name: "без кавычек", header: `3`, want: 3},
+		{name: "слабый тег", header: `W/"3"`, want: 3},
+		{name: "любая версия", header: `*`, want: 0},
+		{name: "любая версия при обязательном заголовке", header: `*`, required: true, want: 0},
+		{name: "несколько тегов", header: `"3", "4"`, wantErr: true},
+		{name: "не число", header: `"abc"`, wantErr: true},
+		{name: "ноль", header: `"0"`, wantErr: true},
+		{name: "отрицательная версия", header: `"-1"`, wantErr: true},
+		{name: "слабый тег в нижнем регистре", header: `w/"3"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/data/1", nil)
+			if tt.header != "" {
+				r.Header.Set("If-Match", tt.header)
+			}
+			s := &Server{RequireIfMatch: tt.required}
+			got, err := s.parseIfMatch(r)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("получено %d, %v, ожидалось %d, ошибка %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestETagRoundTrip(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPut, "/data/1", nil)
+	r.Header.Set("If-Match", etag(42))
+	if got, err := (&Server{}).parseIfMatch(r); err != nil || got != 42 {
+		t.Errorf("If-Match %s: получено %d, %v", etag(42), got, err)
+	}
+}
+
+// versionDB Хранилище, запоминающее ожидаемую версию обновления.
+type versionDB struct {
+	storage.Database
+	current int
+	got     *int
+}
+
+func (db versionDB) UpdateDataByID(ctx context.Context, id int, newData storage.UsersData, version int) (int, error) {
+	*db.got = version
+	if version != 0 && version != db.current {
+		return 0, storage.ErrVersionConflict
+	}
+	return db.current + 1, nil
+}
+
+func TestUpdateDataIfMatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		required    bool
+		wantCode    int
+		wantVersion int
+	}{
+		{name: "без заголовка", wantCode: http.StatusOK},
+		{name: "обязательный заголовок", required: true, wantCode: http.StatusPreconditionRequired, wantVersion: -1},
+		{name: "текущая версия", header: `"3"`, required: true, wantCode: http.StatusOK, wantVersion: 3},
+		{name: "устаревшая версия", header: `"2"`, wantCode: http.StatusPreconditionFailed, wantVersion: 2},
+		{name: "любая версия", header: `*`, required: true, wantCode: http.StatusOK},
+		{name: "некорректный заголовок", header: `"abc"`, wantCode: http.StatusBadRequest, wantVersion: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := -1
+			s := &Server{PG: versionDB{current: 3, got: &got}, RequireIfMatch: tt.required}
+
+			r := httptest.NewRequest(http.MethodPut, "/data/1", strings.NewReader(`{"name":"Иван","surname":"Иванов"}`))
+			if tt.header != "" {
+				r.Header.Set("If-Match", tt.header)
+			}
+			w := httptest.NewRecorder()
+			s.UpdateData(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("код %d, ожидался %d: %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			if got != tt.wantVersion {
+				t.Errorf("в хранилище передана версия %d, ожидалась %d", got, tt.wantVersion)
+			}
+			if w.Code == http.StatusOK && w.Header().Get("ETag") != etag(4) {
+				t.Errorf("ETag %q, ожидался %q", w.Header().Get("ETag"), etag(4))
+			}
+		})
+	}
+}
+
+func TestWriteIfMatchError(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeIfMatchError(w, errIfMatchRequired)
+	if w.Code != http.StatusPreconditionRequired {
+		t.Errorf("код %d, ожидался %d", w.Code, http.StatusPreconditionRequired)
+	}
+
+	w = httptest.NewRecorder()
+	writeIfMatchError(w, errors.New("некорректный заголовок If-Match"))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("код %d, ожидался %d", w.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/xuri/excelize/v2"
+	"github.com/zatrasz75/Service/pkg/auth"
+	"github.com/zatrasz75/Service/pkg/logger"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Форматы выгрузки.
+const (
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+	formatXLSX   = "xlsx"
+)
+
+// Типы содержимого выгрузки.
+const (
+	contentTypeCSV  = "text/csv"
+	contentTypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// xlsxMaxRows максимальное количество строк данных листа XLSX: 1 048 576 строк листа без заголовка.
+const xlsxMaxRows = excelize.TotalRows - 1
+
+// exportFlushEvery количество строк, после которого ответ отправляется клиенту.
+const exportFlushEvery = 1000
+
+// exportColumns заголовки столбцов выгрузки CSV и XLSX.
+var exportColumns = []string{
+	"id", "name", "surname", "patronymic", "age", "gender", "nationality",
+	"version", "created_at", "updated_at", "deleted_at",
+}
+
+// exportRow возвращает значения записи в порядке exportColumns.
+func exportRow(d storage.UsersData) []string {
+	deletedAt := ""
+	if d.DeletedAt != nil {
+		deletedAt = d.DeletedAt.Format(time.RFC3339)
+	}
+	return []string{
+		strconv.Itoa(d.ID), d.Name, d.Surname, d.Patronymic, strconv.Itoa(d.Age), d.Gender, d.Nationality,
+		strconv.Itoa(d.Version), d.CreatedAt.Format(time.RFC3339), d.UpdatedAt.Format(time.RFC3339), deletedAt,
+	}
+}
+
+// exportFormat определяет формат выгрузки по параметру format или заголовку Accept.
+// Если формат не указан, используется CSV.
+func exportFormat(r *http.Request) (string, bool) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		switch format {
+		case formatCSV, formatNDJSON, formatXLSX:
+			return format, true
+		}
+		return "", false
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return formatCSV, true
+	}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case contentTypeCSV, "*/*", "text/*":
+			return formatCSV, true
+		case contentTypeNDJSON, contentTypeNDJSON2:
+			return formatNDJSON, true
+		case contentTypeXLSX:
+			return formatXLSX, true
+		}
+	}
+
+	return "", false
+}
+
+// ExportData Метод для обработки GET-запроса на эндпоинт /data/export.
+// Потоково выгружает записи в формате CSV, NDJSON или XLSX с фильтрами GetData.
+func (s *Server) ExportData(w http.ResponseWriter, r *http.Request) {
+	format, ok := exportFormat(r)
+	if !ok {
+		http.Error(w, "Неподдерживаемый формат выгрузки", http.StatusNotAcceptable)
+		return
+	}
+	filter := parseFilter(r)
+
+	if format == formatXLSX {
+		n, err := s.PG.Count(r.Context(), filter)
+		if err != nil {
+			logger.Error("Ошибка при подсчёте записей для выгрузки", err)
+			http.Error(w, "Ошибка при выгрузке данных", http.StatusInternalServerError)
+			return
+		}
+		if n > xlsxMaxRows {
+			http.Error(w, fmt.Sprintf("Выгрузка содержит %d записей, лист XLSX вмещает не более %d; используйте format=csv или format=ndjson",
+				n, xlsxMaxRows), http.StatusRequestEntityTooLarge)
+			return
+		}
+	}
+
+	// Выгрузка может длиться дольше WriteTimeout сервера.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		logger.Error("Не удалось снять ограничение времени записи ответа", err)
+	}
+
+	filename := "people-" + time.Now().Format("20060102-150405") + "." + format
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	var err error
+	switch format {
+	case formatCSV:
+		w.Header().Set("Content-Type", contentTypeCSV+"; charset=utf-8")
+		err = s.exportCSV(w, rc, r, filter)
+	case formatNDJSON:
+		w.Header().Set("Content-Type", contentTypeNDJSON)
+		err = s.exportNDJSON(w, rc, r, filter)
+	case formatXLSX:
+		w.Header().Set("Content-Type", contentTypeXLSX)
+		err = s.exportXLSX(w, r, filter)
+	}
+	if err != nil {
+		// Заголовки уже могли быть отправлены, поэтому ошибку можно только записать в лог.
+		logger.Error("Ошибка при выгрузке данных", err)
+	}
+}
+
+// exportCSV выгружает записи в формате CSV.
+func (s *Server) exportCSV(w io.Writer, rc *http.ResponseController, r *http.Request, filter storage.Filter) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(exportColumns); err != nil {
+		return err
+	}
+
+	n := 0
+	err := s.PG.Export(r.Context(), filter, func(d storage.UsersData) error {
+		auth.Redact(r.Context(), &d)
+		if err := cw.Write(exportRow(d)); err != nil {
+			return err
+		}
+		n++
+		if n%exportFlushEvery == 0 {
+			cw.Flush()
+			return flush(rc, cw.Error())
+		}
+		return nil
+	})
+	cw.Flush()
+	if err != nil {
+		return err
+	}
+
+	return cw.Error()
+}
+
+// exportNDJSON выгружает записи в формате NDJSON.
+func (s *Server) exportNDJSON(w io.Writer, rc *http.ResponseController, r *http.Request, filter storage.Filter) error {
+	enc := json.NewEncoder(w)
+
+	n := 0
+	return s.PG.Export(r.Context(), filter, func(d storage.UsersData) error {
+		auth.Redact(r.Context(), &d)
+		if err := enc.Encode(d); err != nil {
+			return err
+		}
+		n++
+		if n%exportFlushEvery == 0 {
+			return flush(rc, nil)
+		}
+		return nil
+	})
+}
+
+// exportXLSX выгружает записи в формате XLSX через потоковую запись листа.
+func (s *Server) exportXLSX(w io.Writer, r *http.Request, filter storage.Filter) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := f.GetSheetName(0)
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return err
+	}
+
+	header := make([]interface{}, len(exportColumns))
+	for i, c := range exportColumns {
+		header[i] = c
+	}
+	if err = sw.SetRow("A1", header); err != nil {
+		return err
+	}
+
+	row := 2
+	err = s.PG.Export(r.Context(), filter, func(d storage.UsersData) error {
+		auth.Redact(r.Context(), &d)
+		values := exportRow(d)
+		cells := make([]interface{}, len(values))
+		for i, v := range values {
+			cells[i] = v
+		}
+		cells[0], cells[4], cells[7] = d.ID, d.Age, d.Version
+
+		cell, err := excelize.CoordinatesToCellName(1, row)
+		if err != nil {
+			return err
+		}
+		row++
+		return sw.SetRow(cell, cells)
+	})
+	if err != nil {
+		return err
+	}
+	if err = sw.Flush(); err != nil {
+		return err
+	}
+
+	return f.Write(w)
+}
+
+// flush отправляет накопленные данные клиенту.
+func flush(rc *http.ResponseController, err error) error {
+	if err != nil {
+		return err
+	}
+	if err = rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/zatrasz75/Service/pkg/logger"
+	"github.com/zatrasz75/Service/pkg/service"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"io"
+	"net/http"
+)
+
+// fioMessage Сообщение запроса POST /fio.
+type fioMessage struct {
+	Name       string            `json:"name"`
+	Surname    string            `json:"surname"`
+	Patronymic string            `json:"patronymic"`
+	Key        string            `json:"key"`
+	Headers    map[string]string `json:"headers"`
+}
+
+// PublishFIO Метод для обработки POST-запроса на эндпоинт /fio.
+// Принимает одно сообщение или массив сообщений, проверяет их по правилам потребителя
+// и публикует в топик FIO. Возвращает раздел и смещение каждого сообщения.
+func (s *Server) PublishFIO(w http.ResponseWriter, r *http.Request) {
+	if s.Kafka == nil {
+		http.Error(w, "Публикация недоступна: Kafka не настроена", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		logger.Error("Ошибка при чтении тела запроса", err)
+		writeReadError(w, err, "Ошибка при чтении тела запроса")
+		return
+	}
+
+	// Массив сообщений публикуется пакетом, объект — одним сообщением.
+	batch := bytes.HasPrefix(bytes.TrimSpace(body), []byte("["))
+	var messages []fioMessage
+	if batch {
+		err = json.Unmarshal(body, &messages)
+	} else {
+		var m fioMessage
+		err = json.Unmarshal(body, &m)
+		messages = []fioMessage{m}
+	}
+	if err != nil {
+		logger.Error("Ошибка при чтении JSON", err)
+		http.Error(w, "Ошибка при чтении JSON", http.StatusBadRequest)
+		return
+	}
+	if len(messages) == 0 {
+		http.Error(w, errEmptyBatch.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(messages) > s.maxBulkItems() {
+		http.Error(w, errBatchTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	items := make([]service.PublishItem, len(messages))
+	for i, m := range messages {
+		items[i] = service.PublishItem{
+			Data:    storage.Data{Name: m.Name, Surname: m.Surname, Patronymic: m.Patronymic},
+			Key:     m.Key,
+			Headers: m.Headers,
+		}
+	}
+
+	deliveries, err := s.Kafka.Publish(r.Context(), items)
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": validationErr.Err.Error(),
+			"index": validationErr.Index,
+		})
+		return
+	}
+	if err != nil {
+		logger.Error("Ошибка при отправке сообщения в Kafka", err)
+		http.Error(w, "Ошибка при отправке сообщения в Kafka", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if batch {
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": deliveries})
+		return
+	}
+	json.NewEncoder(w).Encode(deliveries[0])
+}
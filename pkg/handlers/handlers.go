@@ -1,17 +1,47 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"github.com/gorilla/mux"
+	"github.com/zatrasz75/Service/pkg/auth"
+	"github.com/zatrasz75/Service/pkg/feed"
+	"github.com/zatrasz75/Service/pkg/health"
 	"github.com/zatrasz75/Service/pkg/logger"
+	"github.com/zatrasz75/Service/pkg/service"
 	"github.com/zatrasz75/Service/pkg/storage"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"sync"
+	"time"
 )
 
 type Server struct {
 	Server *http.Server
 	PG     storage.Database
+
+	// RequireIfMatch требует заголовок If-Match в запросах PUT и PATCH.
+	RequireIfMatch bool
+	// BulkMaxItems максимальное количество элементов в пакетном запросе.
+	BulkMaxItems int
+	// Kafka клиент для публикации сообщений в топик FIO.
+	Kafka *service.Client
+	// StatsCacheTTL время жизни кеша статистики, 0 отключает кеширование.
+	StatsCacheTTL time.Duration
+	// Feed поток изменений записей для /data/stream.
+	Feed *feed.Hub
+	// StreamHeartbeat период отправки пустых сообщений в потоке изменений.
+	StreamHeartbeat time.Duration
+	// WebhookAllowPrivate разрешает адреса подписчиков в локальных и внутренних сетях.
+	WebhookAllowPrivate bool
+	// Health проверки зависимостей для /readyz.
+	Health *health.Checker
+
+	statsOnce  sync.Once
+	statsCache *statsCache
 }
 
 // Вспомогательная функция для преобразования строки в число с проверкой ошибок.
@@ -24,10 +54,30 @@ func parseQueryParam(param string) int {
 	return value
 }
 
+// parseFilter возвращает параметры фильтрации из строки запроса.
+// Фильтры, недоступные клиенту (см. auth.RestrictFilter), игнорируются.
+func parseFilter(r *http.Request) storage.Filter {
+	return auth.RestrictFilter(r.Context(), storage.Filter{
+		Gender:         r.URL.Query().Get("gender"),
+		IncludeDeleted: r.URL.Query().Get("include_deleted") == "true",
+	})
+}
+
+// auditContext возвращает контекст запроса с информацией об источнике и авторе изменений.
+// Автором считается аутентифицированный клиент, без аутентификации — значение заголовка X-Actor.
+func auditContext(r *http.Request) context.Context {
+	a := storage.Audit{Source: storage.SourceREST, Actor: r.Header.Get("X-Actor")}
+	if p, ok := auth.PrincipalFrom(r.Context()); ok {
+		a.Actor = p.Subject
+		a.Role = p.Role
+	}
+	return storage.WithAudit(r.Context(), a)
+}
+
 // GetData Метод для обработки GET-запроса на эндпоинт /data.
 func (s *Server) GetData(w http.ResponseWriter, r *http.Request) {
 	// Получаем параметры запроса (фильтры и пагинация).
-	genderFilter := r.URL.Query().Get("gender")
+	filter := parseFilter(r)
 	pageStr := r.URL.Query().Get("page")
 	pageSizeStr := r.URL.Query().Get("pageSize")
 
@@ -35,30 +85,61 @@ func (s *Server) GetData(w http.ResponseWriter, r *http.Request) {
 	pageSize := parseQueryParam(pageSizeStr)
 
 	// Вызываем функцию запроса в базу данных с фильтрами и пагинацией.
-	data, err := s.PG.Select(genderFilter, page, pageSize)
+	data, err := s.PG.Select(r.Context(), filter, page, pageSize)
 	if err != nil {
 		logger.Error("Ошибка при выполнении запроса к базе данных", err)
 		http.Error(w, "Ошибка при выполнении запроса к базе данных", http.StatusInternalServerError)
 		return
 	}
+	for i := range data {
+		auth.Redact(r.Context(), &data[i])
+	}
 
 	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(data)
 }
 
+// GetDataByID Метод для обработки GET-запроса на эндпоинт /data/{id}.
+// Возвращает запись с заголовком ETag, содержащим её версию.
+func (s *Server) GetDataByID(w http.ResponseWriter, r *http.Request) {
+	idParam := mux.Vars(r)["id"]
+	id := parseQueryParam(idParam)
+
+	data, err := s.PG.GetDataByID(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Запись не найдена", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Error("Ошибка при выполнении запроса к базе данных", err)
+		http.Error(w, "Ошибка при выполнении запроса к базе данных", http.StatusInternalServerError)
+		return
+	}
+	auth.Redact(r.Context(), &data)
+
+	w.Header().Set("ETag", etag(data.Version))
+	if match := r.Header.Get("If-None-Match"); match != "" && match == etag(data.Version) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+}
+
 // AddData Метод для обработки POST-запроса на эндпоинт /data.
 func (s *Server) AddData(w http.ResponseWriter, r *http.Request) {
 	var newData storage.Data
 	err := json.NewDecoder(r.Body).Decode(&newData)
 	if err != nil {
 		logger.Error("Ошибка при чтении JSON", err)
-		http.Error(w, "Ошибка при чтении JSON", http.StatusBadRequest)
+		writeReadError(w, err, "Ошибка при чтении JSON")
 		return
 	}
 
 	// Сохраняем новые данные в базу данных.
-	id, err := s.PG.SaveDataToDatabase(newData)
+	id, err := s.PG.SaveDataToDatabase(auditContext(r), newData)
 	if err != nil {
 		logger.Error("Ошибка при сохранении данных в базу данных", err)
 		http.Error(w, "Ошибка при сохранении данных в базу данных", http.StatusInternalServerError)
@@ -85,7 +166,11 @@ func (s *Server) DeleteData(w http.ResponseWriter, r *http.Request) {
 	id := parseQueryParam(idParam)
 
 	// Вызываем функцию удаления данных из базы данных по идентификатору.
-	err := s.PG.DeleteDataByID(id)
+	err := s.PG.DeleteDataByID(auditContext(r), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Запись не найдена", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		logger.Error("Ошибка при удалении данных", err)
 		http.Error(w, "Ошибка при удалении данных", http.StatusInternalServerError)
@@ -99,29 +184,67 @@ func (s *Server) DeleteData(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// RestoreData Метод для обработки POST-запроса на эндпоинт /data/{id}/restore.
+// Восстанавливает запись после мягкого удаления.
+func (s *Server) RestoreData(w http.ResponseWriter, r *http.Request) {
+	idParam := mux.Vars(r)["id"]
+	id := parseQueryParam(idParam)
+
+	err := s.PG.RestoreDataByID(auditContext(r), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Удалённая запись не найдена", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Error("Ошибка при восстановлении данных", err)
+		http.Error(w, "Ошибка при восстановлении данных", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Header().Set("Content-Type", "application/json")
+	response := map[string]string{"message": "Данные успешно восстановлены"}
+	json.NewEncoder(w).Encode(response)
+}
+
 // UpdateData Обработчик для HTTP метода PUT для полного обновления сущности.
 func (s *Server) UpdateData(w http.ResponseWriter, r *http.Request) {
 	idParam := mux.Vars(r)["id"]
 	id := parseQueryParam(idParam)
 
+	version, err := s.parseIfMatch(r)
+	if err != nil {
+		writeIfMatchError(w, err)
+		return
+	}
+
 	// Чтение новых данных из тела запроса.
 	var updatedData storage.UsersData
-	err := json.NewDecoder(r.Body).Decode(&updatedData)
+	err = json.NewDecoder(r.Body).Decode(&updatedData)
 	if err != nil {
 		logger.Error("Ошибка при чтении JSON", err)
-		http.Error(w, "Ошибка при чтении JSON", http.StatusBadRequest)
+		writeReadError(w, err, "Ошибка при чтении JSON")
 		return
 	}
 
-	err = s.PG.UpdateDataByID(id, updatedData)
+	newVersion, err := s.PG.UpdateDataByID(auditContext(r), id, updatedData, version)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Запись не найдена", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, storage.ErrVersionConflict) {
+		http.Error(w, "Запись была изменена другим запросом", http.StatusPreconditionFailed)
+		return
+	}
 	if err != nil {
 		logger.Error("Ошибка при обновлении данных", err)
 		http.Error(w, "Ошибка при обновлении данных", http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	w.Header().Set("ETag", etag(newVersion))
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
 	response := map[string]string{"message": "Данные успешно обновлены"}
 	json.NewEncoder(w).Encode(response)
 }
@@ -131,24 +254,115 @@ func (s *Server) PartialUpdateData(w http.ResponseWriter, r *http.Request) {
 	idParam := mux.Vars(r)["id"]
 	id := parseQueryParam(idParam)
 
-	// Чтение частичных данных из тела запроса.
+	version, err := s.parseIfMatch(r)
+	if err != nil {
+		writeIfMatchError(w, err)
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		http.Error(w, "Не указан тип содержимого", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// Чтение документа патча из тела запроса.
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		logger.Error("Ошибка при чтении тела запроса", err)
+		writeReadError(w, err, "Ошибка при чтении тела запроса")
+		return
+	}
+
 	var partialData map[string]interface{}
-	err := json.NewDecoder(r.Body).Decode(&partialData)
+	switch mediaType {
+	case contentTypeMergePatch, contentTypeJSON:
+		partialData, err = mergePatch(r.Context(), body)
+	case contentTypeJSONPatch:
+		// Операции test сравниваются с текущей записью, поэтому обновление
+		// выполняется только если запись не изменилась после чтения.
+		current, getErr := s.PG.GetDataByID(r.Context(), id)
+		if errors.Is(getErr, storage.ErrNotFound) {
+			http.Error(w, "Запись не найдена", http.StatusNotFound)
+			return
+		}
+		if getErr != nil {
+			logger.Error("Ошибка при выполнении запроса к базе данных", getErr)
+			http.Error(w, "Ошибка при выполнении запроса к базе данных", http.StatusInternalServerError)
+			return
+		}
+		if version == 0 {
+			version = current.Version
+		}
+		partialData, err = jsonPatch(r.Context(), body, current)
+	default:
+		w.Header().Set("Accept-Patch", contentTypeMergePatch+", "+contentTypeJSONPatch)
+		http.Error(w, "Неподдерживаемый тип содержимого", http.StatusUnsupportedMediaType)
+		return
+	}
 	if err != nil {
-		logger.Error("Ошибка при чтении JSON", err)
-		http.Error(w, "Ошибка при чтении JSON", http.StatusBadRequest)
+		writePatchError(w, err)
 		return
 	}
 
-	err = s.PG.PartialUpdateDataByID(id, partialData)
+	newVersion, err := s.PG.PartialUpdateDataByID(auditContext(r), id, partialData, version)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Запись не найдена", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, storage.ErrVersionConflict) {
+		http.Error(w, "Запись была изменена другим запросом", http.StatusPreconditionFailed)
+		return
+	}
+	if errors.Is(err, storage.ErrInvalidPatch) {
+		writePatchError(w, err)
+		return
+	}
 	if err != nil {
 		logger.Error("Ошибка при частичном обновлении данных", err)
 		http.Error(w, "Ошибка при частичном обновлении данных", http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	w.Header().Set("ETag", etag(newVersion))
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
 	response := map[string]string{"message": "Данные успешно обновлены"}
 	json.NewEncoder(w).Encode(response)
 }
+
+// GetHistory Метод для обработки GET-запроса на эндпоинт /data/{id}/history.
+func (s *Server) GetHistory(w http.ResponseWriter, r *http.Request) {
+	idParam := mux.Vars(r)["id"]
+	id := parseQueryParam(idParam)
+
+	history, err := s.PG.History(r.Context(), id)
+	if err != nil {
+		logger.Error("Ошибка при получении истории изменений", err)
+		http.Error(w, "Ошибка при получении истории изменений", http.StatusInternalServerError)
+		return
+	}
+	if len(history) == 0 {
+		http.Error(w, "Запись не найдена", http.StatusNotFound)
+		return
+	}
+	for i := range history {
+		history[i].OldValues = auth.RedactValues(r.Context(), history[i].OldValues)
+		history[i].NewValues = auth.RedactValues(r.Context(), history[i].NewValues)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// writeReadError отправляет ответ на ошибку чтения тела запроса: 413, если тело
+// превышает допустимый размер, иначе 400 с сообщением msg.
+func writeReadError(w http.ResponseWriter, err error, msg string) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, "Тело запроса превышает допустимый размер", http.StatusRequestEntityTooLarge)
+		return
+	}
+	http.Error(w, msg, http.StatusBadRequest)
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/zatrasz75/Service/pkg/health"
+	"github.com/zatrasz75/Service/pkg/logger"
+	"net/http"
+)
+
+// Healthz сообщает, что процесс работает. Зависимости не проверяются,
+// чтобы их недоступность не приводила к перезапуску сервиса.
+func (s *Server) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, health.Report{Status: health.StatusOK})
+}
+
+// Readyz сообщает, готов ли сервис принимать запросы, с результатами проверок зависимостей.
+// Если сервис не готов или завершает работу, отвечает 503. Эндпоинт доступен без аутентификации,
+// поэтому ошибки проверок с адресами и учётными данными зависимостей записываются только в лог.
+func (s *Server) Readyz(w http.ResponseWriter, r *http.Request) {
+	report := health.Report{Status: health.StatusOK}
+	if s.Health != nil {
+		report = s.Health.Readiness(r.Context())
+	}
+	for name, res := range report.Checks {
+		if res.Error == "" {
+			continue
+		}
+		logger.Error("Проверка готовности "+name+" не прошла", errors.New(res.Error))
+		res.Error = ""
+		report.Checks[name] = res
+	}
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, status, report)
+}
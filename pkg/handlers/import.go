@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/zatrasz75/Service/pkg/logger"
+	"github.com/zatrasz75/Service/pkg/service"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+)
+
+// importFormat определяет формат файла импорта по параметру format или заголовку Content-Type.
+func importFormat(r *http.Request) (string, bool) {
+	switch r.URL.Query().Get("format") {
+	case service.ImportCSV:
+		return service.ImportCSV, true
+	case service.ImportNDJSON:
+		return service.ImportNDJSON, true
+	case "":
+	default:
+		return "", false
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case contentTypeCSV:
+		return service.ImportCSV, true
+	case contentTypeNDJSON, contentTypeNDJSON2:
+		return service.ImportNDJSON, true
+	}
+
+	return "", false
+}
+
+// ImportData Метод для обработки POST-запроса на эндпоинт /data/import.
+// Сохраняет файл во временный каталог, запускает задачу импорта и сразу возвращает её идентификатор.
+func (s *Server) ImportData(w http.ResponseWriter, r *http.Request) {
+	if s.Kafka == nil {
+		http.Error(w, "Импорт недоступен: Kafka не настроена", http.StatusServiceUnavailable)
+		return
+	}
+	format, ok := importFormat(r)
+	if !ok {
+		http.Error(w, "Неподдерживаемый формат импорта", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// Файл сохраняется целиком, чтобы обработка не зависела от соединения с клиентом.
+	file, err := os.CreateTemp("", "import-*."+format)
+	if err != nil {
+		logger.Error("Ошибка при создании временного файла импорта", err)
+		http.Error(w, "Ошибка при сохранении файла импорта", http.StatusInternalServerError)
+		return
+	}
+	if _, err = io.Copy(file, r.Body); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		logger.Error("Ошибка при чтении файла импорта", err)
+		writeReadError(w, err, "Ошибка при чтении файла импорта")
+		return
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		logger.Error("Ошибка при чтении файла импорта", err)
+		http.Error(w, "Ошибка при сохранении файла импорта", http.StatusInternalServerError)
+		return
+	}
+
+	jobID, err := service.NewImportID()
+	if err == nil {
+		err = s.PG.CreateImportJob(r.Context(), jobID, format)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		logger.Error("Ошибка при создании задачи импорта", err)
+		http.Error(w, "Ошибка при создании задачи импорта", http.StatusInternalServerError)
+		return
+	}
+
+	go func() {
+		defer os.Remove(file.Name())
+		defer file.Close()
+		// Импорт продолжается после ответа, поэтому сохраняет только арендатора и трассу запроса.
+		ctx := trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(r.Context()))
+		ctx = storage.WithTenant(ctx, storage.TenantFrom(r.Context()))
+		s.Kafka.RunImport(ctx, s.PG, jobID, format, file)
+	}()
+
+	w.Header().Set("Location", "/api/v1/data/import/"+jobID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"job_id": jobID, "status": storage.ImportRunning})
+}
+
+// GetImport Метод для обработки GET-запроса на эндпоинт /data/import/{id}.
+// Возвращает прогресс задачи импорта и отклонённые строки.
+func (s *Server) GetImport(w http.ResponseWriter, r *http.Request) {
+	job, err := s.PG.GetImportJob(r.Context(), mux.Vars(r)["id"])
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Задача импорта не найдена", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Error("Ошибка при получении задачи импорта", err)
+		http.Error(w, "Ошибка при получении задачи импорта", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(job)
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/zatrasz75/Service/pkg/auth"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"net/http"
+	"strings"
+)
+
+// Типы содержимого запроса PATCH.
+const (
+	contentTypeJSON       = "application/json"
+	contentTypeMergePatch = "application/merge-patch+json" // RFC 7396
+	contentTypeJSONPatch  = "application/json-patch+json"  // RFC 6902
+)
+
+var (
+	// errMalformedPatch тело запроса не является корректным документом патча.
+	errMalformedPatch = errors.New("некорректный JSON")
+	// errPatchTestFailed операция test не совпала с текущим значением поля.
+	errPatchTestFailed = errors.New("операция test не выполнена")
+	// errHiddenField патч обращается к полю, скрытому от роли клиента.
+	errHiddenField = errors.New("поле скрыто от роли клиента")
+)
+
+// patchOperation Операция JSON Patch.
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// decodeStrict разбирает JSON, сохраняя числа в виде json.Number.
+func decodeStrict(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", errMalformedPatch, err)
+	}
+	if dec.More() {
+		return fmt.Errorf("%w: лишние данные после документа", errMalformedPatch)
+	}
+	return nil
+}
+
+// normalizeField разбирает значение поля и проверяет его по схеме storage.PatchableFields.
+// Поля, скрытые от роли клиента, нельзя ни изменять, ни сравнивать.
+func normalizeField(ctx context.Context, field string, raw json.RawMessage) (interface{}, error) {
+	if _, ok := storage.PatchableFields[field]; !ok {
+		return nil, fmt.Errorf("%w: поле %s нельзя изменять", storage.ErrInvalidPatch, field)
+	}
+	if auth.Hidden(ctx, field) {
+		return nil, fmt.Errorf("%w: %s", errHiddenField, field)
+	}
+
+	var value interface{}
+	if err := decodeStrict(raw, &value); err != nil {
+		return nil, err
+	}
+
+	return storage.NormalizeField(field, value)
+}
+
+// mergePatch разбирает документ JSON Merge Patch в набор изменяемых полей.
+func mergePatch(ctx context.Context, body []byte) (map[string]interface{}, error) {
+	var doc map[string]json.RawMessage
+	if err := decodeStrict(body, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc) == 0 {
+		return nil, fmt.Errorf("%w: нет полей для обновления", storage.ErrInvalidPatch)
+	}
+
+	result := make(map[string]interface{}, len(doc))
+	for field, raw := range doc {
+		value, err := normalizeField(ctx, field, raw)
+		if err != nil {
+			return nil, err
+		}
+		result[field] = value
+	}
+
+	return result, nil
+}
+
+// fieldValues возвращает значения изменяемых полей записи для операции test.
+func fieldValues(data storage.UsersData) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        data.Name,
+		"surname":     data.Surname,
+		"patronymic":  data.Patronymic,
+		"age":         data.Age,
+		"gender":      data.Gender,
+		"nationality": data.Nationality,
+	}
+}
+
+// jsonPatch применяет операции JSON Patch (test, replace, remove) к текущей записи
+// и возвращает набор изменяемых полей.
+func jsonPatch(ctx context.Context, body []byte, current storage.UsersData) (map[string]interface{}, error) {
+	var ops []patchOperation
+	if err := decodeStrict(body, &ops); err != nil {
+		return nil, err
+	}
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("%w: нет операций", storage.ErrInvalidPatch)
+	}
+
+	values := fieldValues(current)
+	result := make(map[string]interface{})
+	for i, op := range ops {
+		field := strings.TrimPrefix(op.Path, "/")
+		if !strings.HasPrefix(op.Path, "/") || strings.Contains(field, "/") {
+			return nil, fmt.Errorf("%w: операция %d: некорректный путь %q", storage.ErrInvalidPatch, i, op.Path)
+		}
+
+		switch op.Op {
+		case "test":
+			expected, err := normalizeField(ctx, field, op.Value)
+			if err != nil {
+				return nil, err
+			}
+			if values[field] != expected {
+				return nil, fmt.Errorf("%w: %s", errPatchTestFailed, op.Path)
+			}
+		case "replace":
+			if op.Value == nil {
+				return nil, fmt.Errorf("%w: операция %d: отсутствует value", storage.ErrInvalidPatch, i)
+			}
+			value, err := normalizeField(ctx, field, op.Value)
+			if err != nil {
+				return nil, err
+			}
+			values[field] = value
+			result[field] = value
+		case "remove":
+			value, err := normalizeField(ctx, field, json.RawMessage("null"))
+			if err != nil {
+				return nil, err
+			}
+			values[field] = value
+			result[field] = value
+		default:
+			return nil, fmt.Errorf("%w: операция %q не поддерживается", storage.ErrInvalidPatch, op.Op)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%w: нет полей для обновления", storage.ErrInvalidPatch)
+	}
+
+	return result, nil
+}
+
+// writePatchError отправляет ответ на ошибку разбора или применения патча.
+func writePatchError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errMalformedPatch):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, errPatchTestFailed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, errHiddenField):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	}
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/zatrasz75/Service/pkg/auth"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// patchPerson текущая запись для операций JSON Patch.
+var patchPerson = storage.UsersData{ID: 1, Name: "Иван", Surname: "Иванов", Patronymic: "Иванович",
+	Age: 42, Gender: "male", Nationality: "RU", Version: 3}
+
+// readerCtx контекст клиента с ролью reader, от которой скрыто поле nationality.
+var readerCtx = auth.WithPrincipal(context.Background(), auth.Principal{
+	Subject: "reader", Role: auth.RoleReader, HiddenFields: map[string]bool{"nationality": true},
+})
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		body    string
+		want    map[string]interface{}
+		wantErr error
+	}{
+		{name: "строки и число", body: `{"name":"Пётр","age":30}`,
+			want: map[string]interface{}{"name": "Пётр", "age": 30}},
+		{name: "null очищает поле", body: `{"patronymic":null,"age":null}`,
+			want: map[string]interface{}{"patronymic": "", "age": 0}},
+		{name: "неизвестное поле", body: `{"email":"a@b.c"}`, wantErr: storage.ErrInvalidPatch},
+		{name: "неизменяемое поле", body: `{"id":2}`, wantErr: storage.ErrInvalidPatch},
+		{name: "строка вместо числа", body: `{"age":"30"}`, wantErr: storage.ErrInvalidPatch},
+		{name: "дробное число", body: `{"age":30.5}`, wantErr: storage.ErrInvalidPatch},
+		{name: "число вместо строки", body: `{"name":1}`, wantErr: storage.ErrInvalidPatch},
+		{name: "пустой документ", body: `{}`, wantErr: storage.ErrInvalidPatch},
+		{name: "массив вместо объекта", body: `[]`, wantErr: errMalformedPatch},
+		{name: "некорректный JSON", body: `{"name":`, wantErr: errMalformedPatch},
+		{name: "лишние данные", body: `{"name":"Пётр"} {}`, wantErr: errMalformedPatch},
+		{name: "скрытое поле", ctx: readerCtx, body: `{"nationality":"KZ"}`, wantErr: errHiddenField},
+		{name: "доступное поле роли", ctx: readerCtx, body: `{"name":"Пётр"}`,
+			want: map[string]interface{}{"name": "Пётр"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			got, err := mergePatch(ctx, []byte(tt.body))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ошибка %v, ожидалась %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("получено %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		body    string
+		want    map[string]interface{}
+		wantErr error
+	}{
+		{name: "replace", body: `[{"op":"replace","path":"/name","value":"Пётр"}]`,
+			want: map[string]interface{}{"name": "Пётр"}},
+		{name: "remove", body: `[{"op":"remove","path":"/age"}]`,
+			want: map[string]interface{}{"age": 0}},
+		{name: "test перед replace", body: `[{"op":"test","path":"/age","value":42},{"op":"replace","path":"/age","value":43}]`,
+			want: map[string]interface{}{"age": 43}},
+		{name: "test после replace видит новое значение",
+			body: `[{"op":"replace","path":"/gender","value":"female"},{"op":"test","path":"/gender","value":"female"}]`,
+			want: map[string]interface{}{"gender": "female"}},
+		{name: "test не выполнен", body: `[{"op":"test","path":"/name","value":"Пётр"},{"op":"replace","path":"/name","value":"Павел"}]`,
+			wantErr: errPatchTestFailed},
+		{name: "только test", body: `[{"op":"test","path":"/name","value":"Иван"}]`, wantErr: storage.ErrInvalidPatch},
+		{name: "replace без value", body: `[{"op":"replace","path":"/name"}]`, wantErr: storage.ErrInvalidPatch},
+		{name: "неверный тип", body: `[{"op":"replace","path":"/age","value":"сорок"}]`, wantErr: storage.ErrInvalidPatch},
+		{name: "путь без косой черты", body: `[{"op":"replace","path":"name","value":"Пётр"}]`, wantErr: storage.ErrInvalidPatch},
+		{name: "вложенный путь", body: `[{"op":"replace","path":"/name/0","value":"Пётр"}]`, wantErr: storage.ErrInvalidPatch},
+		{name: "неизвестное поле", body: `[{"op":"remove","path":"/email"}]`, wantErr: storage.ErrInvalidPatch},
+		{name: "неизменяемое поле", body: `[{"op":"replace","path":"/version","value":1}]`, wantErr: storage.ErrInvalidPatch},
+		{name: "операция add", body: `[{"op":"add","path":"/name","value":"Пётр"}]`, wantErr: storage.ErrInvalidPatch},
+		{name: "операция move", body: `[{"op":"move","from":"/name","path":"/surname"}]`, wantErr: storage.ErrInvalidPatch},
+		{name: "нет операций", body: `[]`, wantErr: storage.ErrInvalidPatch},
+		{name: "объект вместо массива", body: `{"op":"remove","path":"/age"}`, wantErr: errMalformedPatch},
+		{name: "test скрытого поля", ctx: readerCtx, body: `[{"op":"test","path":"/nationality","value":"RU"}]`,
+			wantErr: errHiddenField},
+		{name: "replace скрытого поля", ctx: readerCtx, body: `[{"op":"replace","path":"/nationality","value":"KZ"}]`,
+			wantErr: errHiddenField},
+		{name: "remove скрытого поля", ctx: readerCtx, body: `[{"op":"remove","path":"/nationality"}]`,
+			wantErr: errHiddenField},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			got, err := jsonPatch(ctx, []byte(tt.body), patchPerson)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ошибка %v, ожидалась %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("получено %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeStrict(t *testing.T) {
+	var v interface{}
+	if err := decodeStrict([]byte(`{"age":42}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if age := v.(map[string]interface{})["age"]; age != json.Number("42") {
+		t.Errorf("число разобрано как %T %v, ожидалось json.Number", age, age)
+	}
+
+	for _, body := range []string{``, `{`, `{} []`, `"a" "b"`} {
+		if err := decodeStrict([]byte(body), &v); !errors.Is(err, errMalformedPatch) {
+			t.Errorf("%q: ошибка %v, ожидалась %v", body, err, errMalformedPatch)
+		}
+	}
+}
+
+func TestWritePatchError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{errMalformedPatch, http.StatusBadRequest},
+		{errPatchTestFailed, http.StatusConflict},
+		{errHiddenField, http.StatusForbidden},
+		{storage.ErrInvalidPatch, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		writePatchError(w, tt.err)
+		if w.Code != tt.want {
+			t.Errorf("%v: код %d, ожидался %d", tt.err, w.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/zatrasz75/Service/pkg/auth"
+	"github.com/zatrasz75/Service/pkg/logger"
+	"net/http"
+	"strconv"
+)
+
+// Размер выдачи поиска.
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchData Метод для обработки GET-запроса на эндпоинт /data/search.
+// Ищет людей по имени, фамилии и отчеству с учётом опечаток, транслитерации
+// и уменьшительных форм имён. Поддерживает фильтры GetData.
+func (s *Server) SearchData(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		http.Error(w, "Отсутствует параметр q", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultSearchLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil || n <= 0 {
+			http.Error(w, "Некорректный параметр limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxSearchLimit)
+	}
+
+	results, err := s.PG.Search(r.Context(), query, parseFilter(r), limit)
+	if err != nil {
+		logger.Error("Ошибка при поиске", err)
+		http.Error(w, "Ошибка при поиске", http.StatusInternalServerError)
+		return
+	}
+	for i := range results {
+		auth.Redact(r.Context(), &results[i].UsersData)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(results)
+}
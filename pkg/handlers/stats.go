@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/zatrasz75/Service/pkg/auth"
+	"github.com/zatrasz75/Service/pkg/logger"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultAgeBucket ширина возрастного интервала по умолчанию.
+const defaultAgeBucket = 10
+
+// statsFields поля ответа статистики, рассчитанные по полям записей. Не возвращаются ролям,
+// от которых эти поля скрыты.
+var statsFields = map[string][]string{
+	"gender":      {"by_gender"},
+	"nationality": {"by_nationality"},
+	"age":         {"by_age", "age"},
+}
+
+// statsCache Кеш результатов статистики с ограниченным временем жизни.
+type statsCache struct {
+	mu      sync.Mutex
+	entries map[string]statsEntry
+}
+
+// statsEntry Закешированная статистика.
+type statsEntry struct {
+	stats   storage.Stats
+	expires time.Time
+}
+
+// get возвращает статистику из кеша, если срок её жизни не истёк.
+func (c *statsCache) get(key string) (storage.Stats, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return storage.Stats{}, false
+	}
+	if time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return storage.Stats{}, false
+	}
+	return e.stats, true
+}
+
+// put сохраняет статистику в кеш и удаляет устаревшие записи.
+func (c *statsCache) put(key string, stats storage.Stats, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for k, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = statsEntry{stats: stats, expires: now.Add(ttl)}
+}
+
+// GetStats Метод для обработки GET-запроса на эндпоинт /data/stats.
+// Возвращает количество записей по полу, национальности и возрастным интервалам
+// (ширина задаётся параметром bucket), а также среднее и перцентили возраста.
+func (s *Server) GetStats(w http.ResponseWriter, r *http.Request) {
+	bucket := defaultAgeBucket
+	if bucketStr := r.URL.Query().Get("bucket"); bucketStr != "" {
+		n, err := strconv.Atoi(bucketStr)
+		if err != nil || n <= 0 || n > 150 {
+			http.Error(w, "Некорректный параметр bucket", http.StatusBadRequest)
+			return
+		}
+		bucket = n
+	}
+	filter := parseFilter(r)
+
+	// Статистика кешируется отдельно для каждого арендатора и роли.
+	var role string
+	if p, ok := auth.PrincipalFrom(r.Context()); ok {
+		role = p.Role
+	}
+	key := fmt.Sprintf("%s|%s|%+v|%d", storage.TenantFrom(r.Context()), role, filter, bucket)
+	if s.StatsCacheTTL > 0 {
+		s.statsOnce.Do(func() { s.statsCache = &statsCache{entries: make(map[string]statsEntry)} })
+		if stats, ok := s.statsCache.get(key); ok {
+			w.Header().Set("X-Cache", "HIT")
+			writeStats(w, r, stats)
+			return
+		}
+	}
+
+	stats, err := s.PG.Stats(r.Context(), filter, bucket)
+	if err != nil {
+		logger.Error("Ошибка при расчёте статистики", err)
+		http.Error(w, "Ошибка при расчёте статистики", http.StatusInternalServerError)
+		return
+	}
+
+	if s.StatsCacheTTL > 0 {
+		s.statsCache.put(key, stats, s.StatsCacheTTL)
+		w.Header().Set("X-Cache", "MISS")
+	}
+	writeStats(w, r, stats)
+}
+
+// writeStats отправляет статистику в ответе без групп по полям, скрытым от роли клиента.
+func writeStats(w http.ResponseWriter, r *http.Request, stats storage.Stats) {
+	p, ok := auth.PrincipalFrom(r.Context())
+	if !ok || len(p.HiddenFields) == 0 {
+		writeJSON(w, http.StatusOK, stats)
+		return
+	}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		logger.Error("Ошибка при кодировании статистики", err)
+		http.Error(w, "Ошибка при расчёте статистики", http.StatusInternalServerError)
+		return
+	}
+	var m map[string]json.RawMessage
+	if err = json.Unmarshal(data, &m); err != nil {
+		logger.Error("Ошибка при кодировании статистики", err)
+		http.Error(w, "Ошибка при расчёте статистики", http.StatusInternalServerError)
+		return
+	}
+	for f := range p.HiddenFields {
+		for _, key := range statsFields[f] {
+			delete(m, key)
+		}
+	}
+	writeJSON(w, http.StatusOK, m)
+}
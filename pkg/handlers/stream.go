@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/zatrasz75/Service/pkg/auth"
+	"github.com/zatrasz75/Service/pkg/feed"
+	"github.com/zatrasz75/Service/pkg/logger"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultStreamHeartbeat период отправки пустых сообщений, если он не задан в конфигурации.
+const defaultStreamHeartbeat = 15 * time.Second
+
+// streamWriteTimeout время ожидания записи сообщения WebSocket.
+const streamWriteTimeout = 10 * time.Second
+
+// streamRetry интервал переподключения EventSource в миллисекундах.
+const streamRetry = 3000
+
+var upgrader = websocket.Upgrader{}
+
+// heartbeat возвращает период отправки пустых сообщений.
+func (s *Server) heartbeat() time.Duration {
+	if s.StreamHeartbeat > 0 {
+		return s.StreamHeartbeat
+	}
+	return defaultStreamHeartbeat
+}
+
+// lastEventID возвращает идентификатор последнего полученного клиентом события
+// из заголовка Last-Event-ID или параметра last_event_id. Ноль означает только новые события.
+func lastEventID(r *http.Request) (int64, error) {
+	value := strings.TrimSpace(r.Header.Get("Last-Event-ID"))
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	if value == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("некорректный идентификатор события %q", value)
+	}
+	return id, nil
+}
+
+// watch подписывается на поток изменений. Канал ошибок получает результат Watch после
+// его завершения; подписка прекращается при отмене ctx.
+func (s *Server) watch(ctx context.Context, r *http.Request, after int64) (<-chan feed.Event, <-chan error) {
+	events := make(chan feed.Event)
+	errCh := make(chan error, 1)
+	filter := parseFilter(r)
+
+	go func() {
+		errCh <- s.Feed.Watch(ctx, after, filter, func(e feed.Event) error {
+			auth.Redact(r.Context(), &e.Person)
+			select {
+			case events <- e:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	return events, errCh
+}
+
+// StreamData Метод для обработки GET-запроса на эндпоинт /data/stream.
+// Передаёт изменения записей в формате Server-Sent Events с фильтрами GetData.
+// Поток продолжается с события, следующего за Last-Event-ID.
+func (s *Server) StreamData(w http.ResponseWriter, r *http.Request) {
+	after, err := lastEventID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Поток не ограничен по времени, в отличие от обычных ответов.
+	rc := http.NewResponseController(w)
+	if err = rc.SetWriteDeadline(time.Time{}); err != nil {
+		logger.Error("Не удалось снять ограничение времени записи ответа", err)
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	events, errCh := s.watch(ctx, r, after)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry)
+	if err = flush(rc, nil); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(s.heartbeat())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e := <-events:
+			data, err := json.Marshal(e)
+			if err != nil {
+				logger.Error("Ошибка при кодировании события", err)
+				continue
+			}
+			_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+			if err = flush(rc, err); err != nil {
+				return
+			}
+		case <-ticker.C:
+			_, err = fmt.Fprint(w, ": ping\n\n")
+			if err = flush(rc, err); err != nil {
+				return
+			}
+		case err = <-errCh:
+			if err != nil {
+				logger.Error("Ошибка потока изменений", err)
+			}
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// StreamDataWS Метод для обработки GET-запроса на эндпоинт /data/stream/ws.
+// Передаёт те же события, что и StreamData, через WebSocket. Поток продолжается
+// с события, следующего за last_event_id.
+func (s *Server) StreamDataWS(w http.ResponseWriter, r *http.Request) {
+	after, err := lastEventID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// При ошибке Upgrade сам отправляет ответ клиенту.
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	events, errCh := s.watch(ctx, r, after)
+
+	// Сообщения клиента не ожидаются: чтение нужно для обработки pong и закрытия соединения.
+	heartbeat := s.heartbeat()
+	conn.SetReadDeadline(time.Now().Add(2 * heartbeat))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * heartbeat))
+	})
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e := <-events:
+			conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if err = conn.WriteJSON(e); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)); err != nil {
+				return
+			}
+		case err = <-errCh:
+			if err != nil {
+				logger.Error("Ошибка потока изменений", err)
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "ошибка потока изменений"),
+					time.Now().Add(streamWriteTimeout))
+			}
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
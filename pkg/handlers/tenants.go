@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/zatrasz75/Service/pkg/logger"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// countryRegex код страны ISO 3166-1 alpha-2.
+var countryRegex = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// GetEnrichment Метод для обработки GET-запроса на эндпоинт /admin/enrichment.
+// Возвращает настройки обогащения арендатора без ключа API сервисов обогащения.
+func (s *Server) GetEnrichment(w http.ResponseWriter, r *http.Request) {
+	settings, err := s.PG.EnrichmentSettings(r.Context())
+	if err != nil {
+		logger.Error("Ошибка при получении настроек обогащения", err)
+		http.Error(w, "Ошибка при получении настроек обогащения", http.StatusInternalServerError)
+		return
+	}
+	settings.APIKey = ""
+
+	writeJSON(w, http.StatusOK, settings)
+}
+
+// SetEnrichment Метод для обработки PUT-запроса на эндпоинт /admin/enrichment.
+// Заменяет настройки обогащения арендатора. Пустой api_key сохраняет текущий ключ.
+func (s *Server) SetEnrichment(w http.ResponseWriter, r *http.Request) {
+	var req storage.EnrichmentSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeReadError(w, err, "Ошибка при чтении JSON")
+		return
+	}
+	req.CountryID = strings.ToUpper(strings.TrimSpace(req.CountryID))
+	if req.CountryID != "" && !countryRegex.MatchString(req.CountryID) {
+		http.Error(w, "country_id должен быть кодом страны ISO 3166-1 из двух букв", http.StatusUnprocessableEntity)
+		return
+	}
+
+	settings, err := s.PG.SetEnrichmentSettings(r.Context(), req)
+	if err != nil {
+		logger.Error("Ошибка при сохранении настроек обогащения", err)
+		http.Error(w, "Ошибка при сохранении настроек обогащения", http.StatusInternalServerError)
+		return
+	}
+	settings.APIKey = ""
+
+	writeJSON(w, http.StatusOK, settings)
+}
@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/zatrasz75/Service/pkg/logger"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"github.com/zatrasz75/Service/pkg/webhooks"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Ограничения списка доставок.
+const (
+	defaultDeliveriesLimit = 50
+	maxDeliveriesLimit     = 500
+)
+
+// minWebhookSecret минимальная длина секрета подписки.
+const minWebhookSecret = 16
+
+// errWebhookJSON тело запроса подписки не является корректным JSON.
+var errWebhookJSON = errors.New("некорректный JSON")
+
+// webhookRequest Тело запроса создания и изменения подписки.
+type webhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret"`
+	Active     *bool    `json:"active"`
+}
+
+// webhookEventTypes типы событий, на которые можно подписаться.
+var webhookEventTypes = map[string]bool{
+	storage.EventPersonCreated: true,
+	storage.EventPersonUpdated: true,
+	storage.EventPersonDeleted: true,
+}
+
+// pathID возвращает числовой параметр маршрута.
+func pathID(r *http.Request, name string) int64 {
+	id, _ := strconv.ParseInt(mux.Vars(r)[name], 10, 64)
+	return id
+}
+
+// decodeWebhook читает и проверяет тело запроса подписки. Адреса локальных и внутренних сетей
+// запрещены, если не разрешены настройкой WebhookAllowPrivate.
+func (s *Server) decodeWebhook(r *http.Request) (storage.Webhook, error) {
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return storage.Webhook{}, fmt.Errorf("%w: %w", errWebhookJSON, err)
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return storage.Webhook{}, errors.New("url должен быть абсолютным адресом http или https")
+	}
+	if !s.WebhookAllowPrivate {
+		if err = webhooks.CheckURL(r.Context(), req.URL); err != nil {
+			return storage.Webhook{}, err
+		}
+	}
+	eventTypes := []string{}
+	for _, t := range req.EventTypes {
+		if !webhookEventTypes[t] {
+			return storage.Webhook{}, fmt.Errorf("неизвестный тип события %q", t)
+		}
+		eventTypes = append(eventTypes, t)
+	}
+	if req.Secret != "" && len(req.Secret) < minWebhookSecret {
+		return storage.Webhook{}, fmt.Errorf("секрет должен содержать не менее %d символов", minWebhookSecret)
+	}
+
+	w := storage.Webhook{URL: req.URL, EventTypes: eventTypes, Secret: req.Secret, Active: true}
+	if req.Active != nil {
+		w.Active = *req.Active
+	}
+	return w, nil
+}
+
+// writeWebhookError отправляет ответ на ошибку разбора подписки.
+func writeWebhookError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errWebhookJSON) {
+		writeReadError(w, err, "Ошибка при чтении JSON")
+		return
+	}
+	http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+}
+
+// writeJSON отправляет ответ в формате JSON.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// CreateWebhook Метод для обработки POST-запроса на эндпоинт /webhooks.
+// Если секрет не передан, он генерируется. Секрет возвращается только в этом ответе.
+func (s *Server) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, err := s.decodeWebhook(r)
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	if hook.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err = rand.Read(secret); err != nil {
+			logger.Error("Не удалось сгенерировать секрет подписки", err)
+			http.Error(w, "Не удалось сгенерировать секрет подписки", http.StatusInternalServerError)
+			return
+		}
+		hook.Secret = hex.EncodeToString(secret)
+	}
+
+	hook, err = s.PG.CreateWebhook(r.Context(), hook)
+	if err != nil {
+		logger.Error("Ошибка при создании подписки", err)
+		http.Error(w, "Ошибка при создании подписки", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/webhooks/"+strconv.FormatInt(hook.ID, 10))
+	writeJSON(w, http.StatusCreated, hook)
+}
+
+// ListWebhooks Метод для обработки GET-запроса на эндпоинт /webhooks.
+func (s *Server) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := s.PG.ListWebhooks(r.Context())
+	if err != nil {
+		logger.Error("Ошибка при получении подписок", err)
+		http.Error(w, "Ошибка при получении подписок", http.StatusInternalServerError)
+		return
+	}
+
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	writeJSON(w, http.StatusOK, hooks)
+}
+
+// GetWebhook Метод для обработки GET-запроса на эндпоинт /webhooks/{id}.
+func (s *Server) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, err := s.PG.GetWebhook(r.Context(), pathID(r, "id"))
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Подписка не найдена", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Error("Ошибка при получении подписки", err)
+		http.Error(w, "Ошибка при получении подписки", http.StatusInternalServerError)
+		return
+	}
+
+	hook.Secret = ""
+	writeJSON(w, http.StatusOK, hook)
+}
+
+// UpdateWebhook Метод для обработки PUT-запроса на эндпоинт /webhooks/{id}.
+// Если секрет не передан, сохраняется текущий.
+func (s *Server) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, err := s.decodeWebhook(r)
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	hook.ID = pathID(r, "id")
+
+	hook, err = s.PG.UpdateWebhook(r.Context(), hook)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Подписка не найдена", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Error("Ошибка при изменении подписки", err)
+		http.Error(w, "Ошибка при изменении подписки", http.StatusInternalServerError)
+		return
+	}
+
+	hook.Secret = ""
+	writeJSON(w, http.StatusOK, hook)
+}
+
+// DeleteWebhook Метод для обработки DELETE-запроса на эндпоинт /webhooks/{id}.
+func (s *Server) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	err := s.PG.DeleteWebhook(r.Context(), pathID(r, "id"))
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Подписка не найдена", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Error("Ошибка при удалении подписки", err)
+		http.Error(w, "Ошибка при удалении подписки", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "Подписка успешно удалена"})
+}
+
+// GetWebhookDeliveries Метод для обработки GET-запроса на эндпоинт /webhooks/{id}/deliveries.
+// Возвращает последние доставки, параметр status отбирает доставки в указанном состоянии.
+func (s *Server) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id := pathID(r, "id")
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", storage.DeliveryPending, storage.DeliverySucceeded, storage.DeliveryFailed:
+	default:
+		http.Error(w, "Некорректный параметр status", http.StatusBadRequest)
+		return
+	}
+	limit := defaultDeliveriesLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		limit = min(parseQueryParam(l), maxDeliveriesLimit)
+	}
+
+	if _, err := s.PG.GetWebhook(r.Context(), id); errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Подписка не найдена", http.StatusNotFound)
+		return
+	}
+	deliveries, err := s.PG.WebhookDeliveries(r.Context(), id, status, limit)
+	if err != nil {
+		logger.Error("Ошибка при получении доставок", err)
+		http.Error(w, "Ошибка при получении доставок", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+// GetWebhookDelivery Метод для обработки GET-запроса на эндпоинт /webhooks/{id}/deliveries/{delivery_id}.
+// Возвращает доставку с журналом попыток.
+func (s *Server) GetWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	delivery, err := s.PG.GetWebhookDelivery(r.Context(), pathID(r, "id"), pathID(r, "delivery_id"))
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Доставка не найдена", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Error("Ошибка при получении доставки", err)
+		http.Error(w, "Ошибка при получении доставки", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, delivery)
+}
+
+// ReplayWebhook Метод для обработки POST-запроса на эндпоинт /webhooks/{id}/replay.
+// Повторно отправляет все неудавшиеся доставки подписки.
+func (s *Server) ReplayWebhook(w http.ResponseWriter, r *http.Request) {
+	id := pathID(r, "id")
+	if _, err := s.PG.GetWebhook(r.Context(), id); errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Подписка не найдена", http.StatusNotFound)
+		return
+	}
+
+	n, err := s.PG.ReplayWebhookDeliveries(r.Context(), id, 0)
+	if err != nil {
+		logger.Error("Ошибка при повторной отправке доставок", err)
+		http.Error(w, "Ошибка при повторной отправке доставок", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]int64{"replayed": n})
+}
+
+// ReplayWebhookDelivery Метод для обработки POST-запроса на эндпоинт
+// /webhooks/{id}/deliveries/{delivery_id}/replay. Повторно отправляет неудавшуюся доставку.
+func (s *Server) ReplayWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	id, deliveryID := pathID(r, "id"), pathID(r, "delivery_id")
+	if _, err := s.PG.GetWebhookDelivery(r.Context(), id, deliveryID); errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Доставка не найдена", http.StatusNotFound)
+		return
+	}
+
+	n, err := s.PG.ReplayWebhookDeliveries(r.Context(), id, deliveryID)
+	if err != nil {
+		logger.Error("Ошибка при повторной отправке доставки", err)
+		http.Error(w, "Ошибка при повторной отправке доставки", http.StatusInternalServerError)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "Повторно отправить можно только неудавшуюся доставку", http.StatusConflict)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]int64{"replayed": n})
+}
@@ -0,0 +1,110 @@
+// Package health проверки состояния сервиса и его зависимостей для оркестратора.
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Состояния сервиса и отдельных проверок.
+const (
+	StatusOK           = "ok"
+	StatusDegraded     = "degraded"      // некритичная зависимость недоступна, сервис работает
+	StatusUnavailable  = "unavailable"   // критичная зависимость недоступна
+	StatusShuttingDown = "shutting_down" // сервис завершает работу
+	StatusError        = "error"
+)
+
+// defaultTimeout время выполнения проверок по умолчанию.
+const defaultTimeout = 2 * time.Second
+
+// Check Проверка зависимости.
+type Check struct {
+	Name     string
+	Critical bool // ошибка критичной проверки делает сервис неготовым, некритичной — degraded
+	Check    func(ctx context.Context) error
+}
+
+// Result Результат проверки зависимости.
+type Result struct {
+	Status     string `json:"status"`
+	Critical   bool   `json:"critical"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Report Состояние сервиса с результатами проверок по именам зависимостей.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// Ready сообщает, может ли сервис принимать запросы.
+func (r Report) Ready() bool {
+	return r.Status == StatusOK || r.Status == StatusDegraded
+}
+
+// Checker Выполняет проверки зависимостей.
+type Checker struct {
+	checks       []Check
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+// New создаёт набор проверок. Нулевой timeout означает значение по умолчанию.
+func New(timeout time.Duration, checks ...Check) *Checker {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &Checker{checks: checks, timeout: timeout}
+}
+
+// Shutdown переводит сервис в состояние завершения: дальнейшие проверки готовности не выполняются.
+func (c *Checker) Shutdown() {
+	c.shuttingDown.Store(true)
+}
+
+// Readiness выполняет все проверки одновременно и возвращает состояние сервиса.
+func (c *Checker) Readiness(ctx context.Context) Report {
+	if c.shuttingDown.Load() {
+		return Report{Status: StatusShuttingDown}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	results := make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			start := time.Now()
+			res := Result{Status: StatusOK, Critical: check.Critical}
+			if err := check.Check(ctx); err != nil {
+				res.Status, res.Error = StatusError, err.Error()
+			}
+			res.DurationMS = time.Since(start).Milliseconds()
+			results[i] = res
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(c.checks))}
+	for i, check := range c.checks {
+		res := results[i]
+		report.Checks[check.Name] = res
+		if res.Status == StatusOK {
+			continue
+		}
+		if check.Critical {
+			report.Status = StatusUnavailable
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+
+	return report
+}
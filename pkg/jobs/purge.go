@@ -0,0 +1,33 @@
+package jobs
+
+import (
+	"context"
+	"github.com/zatrasz75/Service/pkg/logger"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"time"
+)
+
+// StartPurge периодически окончательно удаляет записи, помеченные как удалённые дольше retention.
+// Работает до отмены ctx.
+func StartPurge(ctx context.Context, db storage.Database, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	ctx = storage.WithAudit(ctx, storage.Audit{Source: storage.SourceJob, Actor: "purge"})
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := db.PurgeDeleted(ctx, retention)
+			if err != nil {
+				logger.Error("не удалось удалить устаревшие записи", err)
+				continue
+			}
+			if n > 0 {
+				logger.Info("Окончательно удалено записей: %d", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/segmentio/kafka-go"
+	"sync"
+)
+
+var (
+	readerLagDesc = prometheus.NewDesc(namespace+"_kafka_consumer_lag",
+		"Отставание потребителя от последнего сообщения раздела по данным kafka.Reader.Stats().",
+		[]string{"topic", "partition"}, nil)
+	readerQueueDesc = prometheus.NewDesc(namespace+"_kafka_consumer_queue_length",
+		"Количество полученных, но ещё не прочитанных сообщений.",
+		[]string{"topic"}, nil)
+)
+
+// readerCollector Сборщик метрик читателей Kafka.
+type readerCollector struct {
+	readers []*kafka.Reader
+
+	mu  sync.Mutex
+	lag map[[2]string]int64 // последнее отставание по топику и разделу
+}
+
+// NewReaderCollector создаёт сборщик отставания читателей Kafka.
+// Читатель группы потребителей сообщает отставание раздела, из которого читал последним,
+// поэтому значения остальных разделов сохраняются до их следующего обновления.
+func NewReaderCollector(readers ...*kafka.Reader) prometheus.Collector {
+	return &readerCollector{readers: readers, lag: make(map[[2]string]int64)}
+}
+
+// Describe реализует prometheus.Collector.
+func (c *readerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- readerLagDesc
+	ch <- readerQueueDesc
+}
+
+// Collect реализует prometheus.Collector.
+func (c *readerCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, r := range c.readers {
+		stats := r.Stats()
+		if stats.Partition != "" {
+			c.lag[[2]string{stats.Topic, stats.Partition}] = stats.Lag
+		}
+		ch <- prometheus.MustNewConstMetric(readerQueueDesc, prometheus.GaugeValue, float64(stats.QueueLength), stats.Topic)
+	}
+	for key, lag := range c.lag {
+		ch <- prometheus.MustNewConstMetric(readerLagDesc, prometheus.GaugeValue, float64(lag), key[0], key[1])
+	}
+}
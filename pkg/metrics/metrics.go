@@ -0,0 +1,106 @@
+// Package metrics метрики Prometheus сервиса: запросы HTTP, потребитель Kafka,
+// запросы к сервисам обогащения и пул соединений с базой данных.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// namespace префикс имён метрик сервиса.
+const namespace = "service"
+
+// Результаты обработки сообщений Kafka и запросов к сервисам обогащения.
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+	OutcomeInvalid = "invalid" // сообщение отклонено проверкой и отправлено в FIO_FAILED
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Количество запросов HTTP по маршруту, методу и статусу ответа.",
+	}, []string{"route", "method", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Время обработки запросов HTTP по маршруту, методу и статусу ответа.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	messagesProcessed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kafka_messages_processed_total",
+		Help:      "Количество сообщений Kafka, обогащённых и сохранённых в базу данных.",
+	})
+
+	messagesFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kafka_messages_failed_total",
+		Help:      "Количество сообщений Kafka, не прошедших проверку (invalid) или не сохранённых из-за ошибки (error).",
+	}, []string{"reason"})
+
+	messageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "kafka_message_processing_seconds",
+		Help:      "Время обработки сообщения Kafka от получения до подтверждения.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"outcome"})
+
+	enrichmentRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "enrichment_requests_total",
+		Help:      "Количество запросов к сервисам обогащения по сервису и результату.",
+	}, []string{"provider", "outcome"})
+
+	enrichmentDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "enrichment_request_duration_seconds",
+		Help:      "Время запросов к сервисам обогащения по сервису и результату.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"provider", "outcome"})
+)
+
+// Handler возвращает обработчик, отдающий метрики в формате Prometheus.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Register регистрирует дополнительные сборщики метрик.
+func Register(collectors ...prometheus.Collector) {
+	prometheus.MustRegister(collectors...)
+}
+
+// ObserveHTTP учитывает обработанный запрос HTTP. route — шаблон пути маршрута.
+func ObserveHTTP(route, method string, status int, d time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(route, method, code).Inc()
+	httpDuration.WithLabelValues(route, method, code).Observe(d.Seconds())
+}
+
+// ObserveMessage учитывает обработанное сообщение Kafka с результатом outcome.
+func ObserveMessage(outcome string, d time.Duration) {
+	if outcome == OutcomeSuccess {
+		messagesProcessed.Inc()
+	} else {
+		messagesFailed.WithLabelValues(outcome).Inc()
+	}
+	messageDuration.WithLabelValues(outcome).Observe(d.Seconds())
+}
+
+// ObserveEnrichment учитывает запрос к сервису обогащения provider.
+func ObserveEnrichment(provider string, err error, d time.Duration) {
+	outcome := OutcomeSuccess
+	if err != nil {
+		outcome = OutcomeError
+	}
+	enrichmentRequests.WithLabelValues(provider, outcome).Inc()
+	enrichmentDuration.WithLabelValues(provider, outcome).Observe(d.Seconds())
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"github.com/zatrasz75/Service/pkg/logger"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"github.com/zatrasz75/Service/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Форматы файлов импорта.
+const (
+	ImportCSV    = "csv"
+	ImportNDJSON = "ndjson"
+)
+
+// Заголовки сообщений Kafka, по которым отказы сопоставляются с задачами импорта.
+const (
+	HeaderImportJob = "import-job-id"
+	HeaderImportRow = "import-row"
+)
+
+// importBatchSize количество сообщений, отправляемых в Kafka за один вызов.
+const importBatchSize = 100
+
+// importRow Строка файла импорта.
+type importRow struct {
+	row  int
+	data storage.Data
+	err  error
+}
+
+// NewImportID создаёт идентификатор задачи импорта.
+func NewImportID() (string, error) {
+	return randomID()
+}
+
+// randomID возвращает случайный идентификатор из 32 шестнадцатеричных символов.
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// readImportRows читает строки файла импорта и передаёт их в fn.
+// CSV содержит столбцы name, surname, patronymic; строка заголовка необязательна.
+func readImportRows(format string, r io.Reader, fn func(importRow) error) error {
+	switch format {
+	case ImportCSV:
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		cr.TrimLeadingSpace = true
+		for row := 1; ; row++ {
+			record, err := cr.Read()
+			if err == io.EOF {
+				return nil
+			}
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				if err = fn(importRow{row: row, err: err}); err != nil {
+					return err
+				}
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if row == 1 && len(record) > 0 && strings.EqualFold(strings.TrimSpace(record[0]), "name") {
+				continue
+			}
+			if len(record) < 2 || len(record) > 3 {
+				if err = fn(importRow{row: row, err: fmt.Errorf("ожидается 2 или 3 столбца, получено %d", len(record))}); err != nil {
+					return err
+				}
+				continue
+			}
+			d := storage.Data{Name: strings.TrimSpace(record[0]), Surname: strings.TrimSpace(record[1])}
+			if len(record) == 3 {
+				d.Patronymic = strings.TrimSpace(record[2])
+			}
+			if err = fn(importRow{row: row, data: d}); err != nil {
+				return err
+			}
+		}
+	case ImportNDJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for row := 1; scanner.Scan(); row++ {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			var d storage.Data
+			item := importRow{row: row}
+			if err := json.Unmarshal([]byte(line), &d); err != nil {
+				item.err = err
+			} else {
+				item.data = storage.Data{Name: d.Name, Surname: d.Surname, Patronymic: d.Patronymic}
+			}
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		return scanner.Err()
+	}
+
+	return fmt.Errorf("неподдерживаемый формат импорта: %s", format)
+}
+
+// RunImport читает файл импорта и публикует каждую строку сообщением в топик FIO
+// с арендатором из ctx. Прогресс и отклонённые строки сохраняются в задаче jobID.
+func (c *Client) RunImport(ctx context.Context, db storage.Database, jobID, format string, r io.Reader) {
+	ctx, span := tracing.Start(ctx, "import "+format, trace.WithAttributes(attribute.String("import.job_id", jobID)))
+
+	total, published := 0, 0
+	var batch []kafka.Message
+	var batchRows []importRow
+
+	publish := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		// Строка считается опубликованной только после подтверждения записи брокером.
+		// Строки, запись которых не подтверждена, отклоняются на этапе publish.
+		err := c.Writer.WriteMessages(ctx, batch...)
+		var writeErrs kafka.WriteErrors
+		switch {
+		case err == nil:
+			published += len(batch)
+		case errors.As(err, &writeErrs) && len(writeErrs) == len(batch):
+			logger.Error("Ошибка отправки строк импорта в Kafka", err)
+			for i, row := range batchRows {
+				if writeErrs[i] == nil {
+					published++
+					continue
+				}
+				rejectRow(ctx, db, jobID, row, storage.StagePublish, writeErrs[i].Error())
+			}
+		default:
+			logger.Error("Ошибка отправки строк импорта в Kafka", err)
+			for _, row := range batchRows {
+				rejectRow(ctx, db, jobID, row, storage.StagePublish, err.Error())
+			}
+		}
+		batch, batchRows = batch[:0], batchRows[:0]
+
+		return db.UpdateImportJob(ctx, jobID, total, published)
+	}
+
+	err := readImportRows(format, r, func(row importRow) error {
+		total++
+		if row.err != nil {
+			rejectRow(ctx, db, jobID, row, storage.StageParse, row.err.Error())
+			return nil
+		}
+
+		value, err := json.Marshal(row.data)
+		if err != nil {
+			return err
+		}
+		headers := []kafka.Header{
+			{Key: HeaderImportJob, Value: []byte(jobID)},
+			{Key: HeaderImportRow, Value: []byte(strconv.Itoa(row.row))},
+			{Key: HeaderTenant, Value: []byte(storage.TenantFrom(ctx))},
+		}
+		tracing.InjectKafka(ctx, &headers)
+		batch = append(batch, kafka.Message{Value: value, Headers: headers})
+		batchRows = append(batchRows, row)
+		if len(batch) >= importBatchSize {
+			return publish()
+		}
+		return nil
+	})
+	if err == nil {
+		err = publish()
+	}
+
+	tracing.End(span, err, attribute.Int("import.rows", total), attribute.Int("import.published", published))
+
+	status, errMsg := storage.ImportCompleted, ""
+	if err != nil {
+		logger.Error("Ошибка импорта "+jobID, err)
+		status, errMsg = storage.ImportFailed, err.Error()
+		if updErr := db.UpdateImportJob(ctx, jobID, total, published); updErr != nil {
+			logger.Error("Не удалось сохранить прогресс импорта", updErr)
+		}
+	}
+	if err = db.FinishImportJob(ctx, jobID, status, errMsg); err != nil {
+		logger.Error("Не удалось завершить задачу импорта", err)
+	}
+}
+
+// rejectRow сохраняет отклонённую строку импорта.
+func rejectRow(ctx context.Context, db storage.Database, jobID string, row importRow, stage, reason string) {
+	err := db.AddImportRejection(ctx, jobID, storage.ImportRejection{
+		Row:        row.row,
+		Stage:      stage,
+		Name:       row.data.Name,
+		Surname:    row.data.Surname,
+		Patronymic: row.data.Patronymic,
+		Reason:     reason,
+	})
+	if err != nil {
+		logger.Error("Не удалось сохранить отклонённую строку импорта", err)
+	}
+}
+
+// headerValue возвращает значение заголовка сообщения Kafka.
+func headerValue(msg kafka.Message, key string) string {
+	for _, h := range msg.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// fetchRejection читает сообщение из FIO_FAILED и, если оно относится к задаче импорта,
+// сохраняет отказ в этой задаче. Отказ сохраняется только в задаче арендатора из заголовка сообщения.
+func (c *Client) fetchRejection(db storage.Database) error {
+	msg, err := c.RejectionReader.FetchMessage(context.Background())
+	if err != nil {
+		return err
+	}
+
+	jobID := headerValue(msg, HeaderImportJob)
+	if jobID != "" {
+		row, _ := strconv.Atoi(headerValue(msg, HeaderImportRow))
+		var r storage.Data
+		if err = json.Unmarshal(msg.Value, &r); err != nil {
+			logger.Error("Ошибка разбора JSON: ", err)
+		}
+		tenant := headerValue(msg, HeaderTenant)
+		if tenant == "" {
+			tenant = storage.DefaultTenant
+		}
+		err = db.AddImportRejection(storage.WithTenant(context.Background(), tenant), jobID, storage.ImportRejection{
+			Row:        row,
+			Stage:      storage.StageValidation,
+			Name:       r.Name,
+			Surname:    r.Surname,
+			Patronymic: r.Patronymic,
+			Reason:     r.Err,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.RejectionReader.CommitMessages(context.Background(), msg)
+}
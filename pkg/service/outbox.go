@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/segmentio/kafka-go"
+	"github.com/zatrasz75/Service/pkg/logger"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"strconv"
+	"time"
+)
+
+// Значения по умолчанию для публикации событий из outbox.
+const (
+	defaultOutboxInterval  = time.Second
+	defaultOutboxBatchSize = 100
+)
+
+// RelayOutbox публикует события изменения записей из outbox в топик topic до отмены ctx.
+// Событие удаляется из outbox только после подтверждения записи всеми репликами раздела,
+// поэтому доставка выполняется не менее одного раза. Ключ сообщения — идентификатор записи,
+// так что события одной записи попадают в один раздел в порядке их создания.
+func (c *Client) RelayOutbox(ctx context.Context, db storage.Database, topic string, interval time.Duration, batchSize int) {
+	if interval <= 0 {
+		interval = defaultOutboxInterval
+	}
+	if batchSize <= 0 {
+		batchSize = defaultOutboxBatchSize
+	}
+
+	w := &kafka.Writer{
+		Addr:         kafka.TCP(c.Broker),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchTimeout: 10 * time.Millisecond,
+	}
+	defer w.Close()
+
+	publish := func(events []storage.OutboxEvent) error {
+		messages := make([]kafka.Message, len(events))
+		for i, e := range events {
+			value, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			messages[i] = kafka.Message{
+				Key:   []byte(strconv.Itoa(e.PersonID)),
+				Value: value,
+				Headers: []kafka.Header{
+					{Key: "event-id", Value: []byte(strconv.FormatInt(e.ID, 10))},
+					{Key: "event-type", Value: []byte(e.Type)},
+				},
+				Time: e.OccurredAt,
+			}
+		}
+		return w.WriteMessages(ctx, messages...)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := db.RelayOutbox(ctx, batchSize, publish)
+		if err != nil && ctx.Err() == nil {
+			logger.Error("не удалось опубликовать события из outbox", err)
+		}
+		// Полный пакет означает, что в outbox могут остаться события.
+		if err == nil && n == batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"github.com/zatrasz75/Service/pkg/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+	"sync"
+)
+
+// HeaderMessageID заголовок с уникальным идентификатором публикуемого сообщения.
+// По нему результат доставки сопоставляется с вызовом Publish.
+const HeaderMessageID = "message-id"
+
+// HeaderTenant заголовок с арендатором, которому принадлежит запись из сообщения.
+const HeaderTenant = "tenant-id"
+
+// reservedHeader сообщает, устанавливается ли заголовок сервисом: идентификатор сообщения,
+// арендатор, задача импорта и контекст трассы. Такие заголовки нельзя передать в Publish.
+func reservedHeader(key string) bool {
+	switch strings.ToLower(key) {
+	case HeaderMessageID, HeaderTenant, HeaderImportJob, HeaderImportRow:
+		return true
+	}
+	for _, f := range tracing.Fields() {
+		if strings.EqualFold(key, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// PublishItem Сообщение с ФИО для публикации в топик FIO.
+type PublishItem struct {
+	Data    storage.Data
+	Key     string
+	Headers map[string]string
+}
+
+// Delivery Результат доставки сообщения в Kafka.
+type Delivery struct {
+	Key       string `json:"key,omitempty"`
+	Partition int    `json:"partition"`
+	Offset    int64  `json:"offset"`
+}
+
+// ValidationError Ошибка проверки сообщения в пакете.
+type ValidationError struct {
+	Index int
+	Err   error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("сообщение %d: %v", e.Index, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// deliveries Ожидающие результата доставки сообщения.
+type deliveries struct {
+	pending sync.Map // message-id -> chan kafka.Message
+}
+
+// complete вызывается kafka.Writer после доставки пакета и передаёт раздел и смещение
+// ожидающим вызовам Publish.
+func (d *deliveries) complete(messages []kafka.Message, err error) {
+	if err != nil {
+		return
+	}
+	for _, m := range messages {
+		id := headerValue(m, HeaderMessageID)
+		if id == "" {
+			continue
+		}
+		if ch, ok := d.pending.LoadAndDelete(id); ok {
+			ch.(chan kafka.Message) <- m
+		}
+	}
+}
+
+// Publish проверяет сообщения по правилам потребителя и публикует их в топик FIO
+// с арендатором из ctx в заголовке HeaderTenant. Заголовки, устанавливаемые сервисом, в сообщениях
+// не допускаются.
+// Если хотя бы одно сообщение некорректно, ничего не отправляется и возвращается *ValidationError.
+func (c *Client) Publish(ctx context.Context, items []PublishItem) (result []Delivery, err error) {
+	ctx, span := tracing.Start(ctx, c.Writer.Topic+" publish", trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(semconv.MessagingSystemKafka, semconv.MessagingDestinationName(c.Writer.Topic),
+			semconv.MessagingBatchMessageCount(len(items))))
+	defer func() { tracing.End(span, err) }()
+
+	msgs := make([]kafka.Message, len(items))
+	ids := make([]string, len(items))
+	for i, item := range items {
+		if _, err := validateAndEnrichMessage(item.Data); err != nil {
+			return nil, &ValidationError{Index: i, Err: err}
+		}
+		for k := range item.Headers {
+			if reservedHeader(k) {
+				return nil, &ValidationError{Index: i, Err: fmt.Errorf("заголовок %s устанавливается сервисом", k)}
+			}
+		}
+
+		value, err := json.Marshal(storage.Data{
+			Name:       item.Data.Name,
+			Surname:    item.Data.Surname,
+			Patronymic: item.Data.Patronymic,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		ids[i], err = randomID()
+		if err != nil {
+			return nil, err
+		}
+		headers := []kafka.Header{
+			{Key: HeaderMessageID, Value: []byte(ids[i])},
+			{Key: HeaderTenant, Value: []byte(storage.TenantFrom(ctx))},
+		}
+		for k, v := range item.Headers {
+			headers = append(headers, kafka.Header{Key: k, Value: []byte(v)})
+		}
+		tracing.InjectKafka(ctx, &headers)
+
+		msgs[i] = kafka.Message{Value: value, Headers: headers}
+		if item.Key != "" {
+			msgs[i].Key = []byte(item.Key)
+		}
+	}
+
+	chans := make([]chan kafka.Message, len(ids))
+	for i, id := range ids {
+		chans[i] = make(chan kafka.Message, 1)
+		c.deliveries.pending.Store(id, chans[i])
+	}
+	defer func() {
+		for _, id := range ids {
+			c.deliveries.pending.Delete(id)
+		}
+	}()
+
+	if err := c.Writer.WriteMessages(ctx, msgs...); err != nil {
+		return nil, err
+	}
+
+	// В синхронном режиме Completion вызывается до возврата из WriteMessages.
+	result = make([]Delivery, len(items))
+	for i, ch := range chans {
+		select {
+		case m := <-ch:
+			result[i] = Delivery{Key: items[i].Key, Partition: m.Partition, Offset: m.Offset}
+		default:
+			return nil, errors.New("не получен результат доставки сообщения")
+		}
+	}
+
+	return result, nil
+}
@@ -1,66 +1,137 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/zatrasz75/Service/pkg/logger"
+	"github.com/zatrasz75/Service/pkg/metrics"
 	"github.com/zatrasz75/Service/pkg/storage"
+	"github.com/zatrasz75/Service/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"net"
 	"net/http"
+	"net/url"
+	"sync"
+	"time"
 )
 
-func getAge(name string) (int, error) {
-	ageURL := fmt.Sprintf("https://api.agify.io/?name=%s", name)
-	ageResponse, err := http.Get(ageURL)
+// Адреса сервисов обогащения.
+const (
+	agifyURL       = "https://api.agify.io/"
+	genderizeURL   = "https://api.genderize.io/"
+	nationalizeURL = "https://api.nationalize.io/"
+)
+
+// enrichURL формирует адрес запроса к сервису обогащения с учётом настроек арендатора.
+// Код страны передаётся только сервисам, которые его поддерживают.
+func enrichURL(base, name string, settings storage.EnrichmentSettings, withCountry bool) string {
+	q := url.Values{"name": {name}}
+	if withCountry && settings.CountryID != "" {
+		q.Set("country_id", settings.CountryID)
+	}
+	if settings.APIKey != "" {
+		q.Set("apikey", settings.APIKey)
+	}
+	return base + "?" + q.Encode()
+}
+
+// getJSON выполняет запрос к сервису обогащения provider и разбирает ответ в v.
+// Время и результат запроса учитываются в метриках и трассе.
+func getJSON(ctx context.Context, provider, address string, v interface{}) (err error) {
+	ctx, span := tracing.Start(ctx, "GET "+provider, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("enrichment.provider", provider)))
+	start := time.Now()
+	defer func() {
+		metrics.ObserveEnrichment(provider, err, time.Since(start))
+		tracing.End(span, err)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
 	if err != nil {
-		logger.Error("Ошибка при запросе возраста:", err)
-		return 0, err
+		return err
 	}
-	defer ageResponse.Body.Close()
 
-	var ageData storage.Data
-	err = json.NewDecoder(ageResponse.Body).Decode(&ageData)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		logger.Error("Ошибка при чтении ответа возраста:", err)
-		return 0, err
+		// Адрес запроса не попадает в журнал, так как содержит ключ API арендатора.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("%s: %w", provider, urlErr.Err)
+		}
+		return err
 	}
+	defer resp.Body.Close()
 
-	return ageData.Age, err
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s ответил статусом %d", provider, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
 }
 
-func getGender(name string) (string, error) {
-	genderURL := fmt.Sprintf("https://api.genderize.io/?name=%s", name)
-	genderResponse, err := http.Get(genderURL)
+func getAge(ctx context.Context, name string, settings storage.EnrichmentSettings) (int, error) {
+	var ageData storage.Data
+	err := getJSON(ctx, "agify", enrichURL(agifyURL, name, settings, true), &ageData)
 	if err != nil {
-		logger.Error("Ошибка при запросе пола:", err)
-		return "", err
+		logger.Error("Ошибка при запросе возраста:", err)
+		return 0, err
 	}
-	defer genderResponse.Body.Close()
 
+	return ageData.Age, nil
+}
+
+func getGender(ctx context.Context, name string, settings storage.EnrichmentSettings) (string, error) {
 	var genderData storage.Data
-	err = json.NewDecoder(genderResponse.Body).Decode(&genderData)
+	err := getJSON(ctx, "genderize", enrichURL(genderizeURL, name, settings, true), &genderData)
 	if err != nil {
-		logger.Error("Ошибка при чтении ответа пола:", err)
+		logger.Error("Ошибка при запросе пола:", err)
 		return "", err
 	}
 
-	return genderData.Gender, err
+	return genderData.Gender, nil
 }
 
-func getNationalities(name string) (string, error) {
-	nationalityURL := fmt.Sprintf("https://api.nationalize.io/?name=%s", name)
-	nationalityResponse, err := http.Get(nationalityURL)
+func getNationalities(ctx context.Context, name string, settings storage.EnrichmentSettings) (string, error) {
+	var nationalityData storage.Data
+	err := getJSON(ctx, "nationalize", enrichURL(nationalizeURL, name, settings, false), &nationalityData)
 	if err != nil {
 		logger.Error("Ошибка при запросе национальности:", err)
 		return "", err
 	}
-	defer nationalityResponse.Body.Close()
 
-	var nationalityData storage.Data
-	err = json.NewDecoder(nationalityResponse.Body).Decode(&nationalityData)
-	if err != nil {
-		logger.Error("Ошибка при чтении ответа национальности:", err)
-		return "", err
+	return nationalityData.Nationality, nil
+}
+
+// PingEnrichment проверяет, что сервисы обогащения доступны по сети.
+// Запросы к API не выполняются, чтобы не расходовать лимит запросов.
+func PingEnrichment(ctx context.Context) error {
+	addresses := []string{agifyURL, genderizeURL, nationalizeURL}
+	errs := make([]error, len(addresses))
+
+	var wg sync.WaitGroup
+	for i, address := range addresses {
+		wg.Add(1)
+		go func(i int, address string) {
+			defer wg.Done()
+			u, err := url.Parse(address)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			var d net.Dialer
+			conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), "443"))
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", u.Hostname(), err)
+				return
+			}
+			conn.Close()
+		}(i, address)
 	}
+	wg.Wait()
 
-	return nationalityData.Nationality, err
+	return errors.Join(errs...)
 }
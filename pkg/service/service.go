@@ -7,10 +7,15 @@ import (
 	"fmt"
 	"github.com/segmentio/kafka-go"
 	"github.com/zatrasz75/Service/pkg/logger"
+	"github.com/zatrasz75/Service/pkg/metrics"
 	"github.com/zatrasz75/Service/pkg/storage"
-	"github.com/zatrasz75/Service/pkg/storage/postgres"
+	"github.com/zatrasz75/Service/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 	"regexp"
 	"sync"
+	"time"
 )
 
 // Client — клиент Kafka.
@@ -22,8 +27,17 @@ type Client struct {
 	Writer      *kafka.Writer
 	ErrorWriter *kafka.Writer
 
+	// RejectionReader читает топик FIO_FAILED для сопоставления отказов с задачами импорта.
+	RejectionReader *kafka.Reader
+
 	// Broker адрес брокера Kafka.
 	Broker string
+
+	// topicErr топик сообщений, не прошедших проверку (FIO_FAILED).
+	topicErr string
+
+	// deliveries результаты доставки сообщений, опубликованных через Publish.
+	deliveries *deliveries
 }
 
 // New создаёт и инициализирует клиента Kafka.
@@ -33,7 +47,7 @@ func New(brokers []string, topic string, topicErr string, groupId string) (*Clie
 		return nil, errors.New("не указаны параметры подключения к Kafka")
 	}
 
-	c := Client{}
+	c := Client{deliveries: &deliveries{}, topicErr: topicErr}
 
 	// Инициализация компонента получения сообщений.
 	c.Reader = kafka.NewReader(kafka.ReaderConfig{
@@ -43,14 +57,29 @@ func New(brokers []string, topic string, topicErr string, groupId string) (*Clie
 		MinBytes: 10e1,
 		MaxBytes: 10e6,
 	})
+	// Отдельная группа потребителей, чтобы не мешать другим читателям FIO_FAILED.
+	c.RejectionReader = kafka.NewReader(kafka.ReaderConfig{
+		Brokers:  brokers,
+		Topic:    topicErr,
+		GroupID:  groupId + "-import",
+		MinBytes: 10e1,
+		MaxBytes: 10e6,
+	})
 	// Сохраняем адрес брокера.
 	c.Broker = brokers[0]
 
 	// Инициализация компонента отправки сообщений в топик FIO.
+	// Сообщения с ключом попадают в раздел по хешу ключа, без ключа распределяются по кругу.
+	// Короткое ожидание пакета, чтобы синхронная публикация по HTTP не задерживалась.
+	// Запись подтверждается всеми репликами: без подтверждения брокер не возвращает раздел
+	// и смещение сообщения, а ошибки записи на его стороне не видны.
 	c.Writer = &kafka.Writer{
-		Addr:     kafka.TCP(brokers[0]),
-		Topic:    topic,
-		Balancer: &kafka.LeastBytes{},
+		Addr:         kafka.TCP(brokers[0]),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		BatchTimeout: 10 * time.Millisecond,
+		RequiredAcks: kafka.RequireAll,
+		Completion:   c.deliveries.complete,
 	}
 	// Инициализация компонента отправки сообщений в топик FIO_FAILED.
 	c.ErrorWriter = &kafka.Writer{
@@ -62,8 +91,32 @@ func New(brokers []string, topic string, topicErr string, groupId string) (*Clie
 	return &c, nil
 }
 
-// sendErrorMessage отправляет сообщение с ошибкой в очередь FIO_FAILED.
-func (c *Client) sendErrorMessage(msg kafka.Message, errorTopic string, fio storage.Data) error {
+// Ping проверяет соединение с брокером Kafka и наличие разделов читаемого топика.
+func (c *Client) Ping(ctx context.Context) error {
+	conn, err := kafka.DialContext(ctx, "tcp", c.Broker)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err = conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+
+	topic := c.Reader.Config().Topic
+	partitions, err := conn.ReadPartitions(topic)
+	if err != nil {
+		return err
+	}
+	if len(partitions) == 0 {
+		return fmt.Errorf("топик %s не найден", topic)
+	}
+	return nil
+}
+
+// sendErrorMessage отправляет сообщение с ошибкой в топик errorTopic (FIO_FAILED).
+func (c *Client) sendErrorMessage(ctx context.Context, msg kafka.Message, errorTopic string, fio storage.Data) error {
 	var r storage.Data
 	if err := json.Unmarshal(msg.Value, &r); err != nil {
 		logger.Error("Ошибка разбора JSON: ", err)
@@ -85,8 +138,14 @@ func (c *Client) sendErrorMessage(msg kafka.Message, errorTopic string, fio stor
 		Topic:   errorTopic,
 	})
 
-	err = writer.WriteMessages(context.Background(), kafka.Message{
-		Value: errorMessageJSON,
+	// Заголовки исходного сообщения сохраняются, чтобы отказ можно было сопоставить с источником.
+	// Контекст трассы заменяется на текущий.
+	headers := append([]kafka.Header(nil), msg.Headers...)
+	tracing.InjectKafka(ctx, &headers)
+	err = writer.WriteMessages(ctx, kafka.Message{
+		Key:     msg.Key,
+		Value:   errorMessageJSON,
+		Headers: headers,
 	})
 	if err != nil {
 		logger.Error("Ошибка отправки сообщения с ошибкой: %v\n", err)
@@ -125,91 +184,130 @@ func (c *Client) fetchProcessCommit(db storage.Database) error {
 			logger.Error("Ошибка получения сообщения из Kafka: ", err)
 			return err
 		}
+		start := time.Now()
+
+		// Обработка продолжает трассу отправителя из заголовков сообщения.
+		ctx, span := tracing.Start(tracing.ExtractKafka(context.Background(), msg.Headers), msg.Topic+" process",
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(semconv.MessagingSystemKafka, semconv.MessagingDestinationName(msg.Topic),
+				semconv.MessagingKafkaDestinationPartition(msg.Partition), semconv.MessagingKafkaMessageOffset(int(msg.Offset))))
 
 		var r storage.Data
 		err = json.Unmarshal(msg.Value, &r)
 		if err != nil {
 			logger.Error("Ошибка разбора JSON: %v\n", err)
 		}
+
+		// Запись сохраняется для арендатора из заголовка сообщения.
+		tenant := headerValue(msg, HeaderTenant)
+		if tenant == "" {
+			tenant = storage.DefaultTenant
+		}
+		ctx = storage.WithTenant(ctx, tenant)
+		ctx = storage.WithAudit(ctx, storage.Audit{Source: storage.SourceKafka, Actor: c.Reader.Config().GroupID})
+
 		_, err = validateAndEnrichMessage(r)
+		if err == nil && !storage.ValidTenant(tenant) {
+			err = errors.New("некорректное сообщение: некорректный идентификатор арендатора")
+		}
 		if err != nil {
 			// отправляем сообщение с ошибкой в FIO_FAILED
 			r.Err = err.Error()
-			fmt.Println(r.Err)
-			err = c.sendErrorMessage(msg, "FIO_FAILED", r)
+			logger.Info("Сообщение отклонено: %s", r.Err)
+			err = c.sendErrorMessage(ctx, msg, c.topicErr, r)
+			metrics.ObserveMessage(metrics.OutcomeInvalid, time.Since(start))
+			span.SetAttributes(attribute.String("fio.error", r.Err))
 		} else {
+			// Набор сервисов обогащения и их параметры задаются настройками арендатора.
+			settings, err := db.EnrichmentSettings(ctx)
+			if err != nil {
+				logger.Error("не удалось получить настройки обогащения", err)
+				metrics.ObserveMessage(metrics.OutcomeError, time.Since(start))
+				tracing.End(span, err)
+				return err
+			}
+
 			var wg sync.WaitGroup
-			wg.Add(3)
-
-			go func() {
-				defer wg.Done()
-				age, err := getAge(r.Name)
-				if err != nil {
-					logger.Error("не удалось выполнить запрос ", err)
-				}
-				r.Age = age
-				logger.Info("Возраст: %d", age)
-			}()
-			go func() {
-				defer wg.Done()
-				gender, err := getGender(r.Name)
-				if err != nil {
-					logger.Error("не удалось выполнить запрос ", err)
-				}
-				r.Gender = gender
-				logger.Info("Пол: %s", gender)
-			}()
-			go func() {
-				defer wg.Done()
-				nationalities, err := getNationalities(r.Name)
-				if err != nil {
-					logger.Error("не удалось выполнить запрос ", err)
-				}
-				r.Nationality = nationalities
-				logger.Info("Национальность: %s", nationalities)
-			}()
+
+			if settings.Age {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					age, err := getAge(ctx, r.Name, settings)
+					if err != nil {
+						logger.Error("не удалось выполнить запрос ", err)
+					}
+					r.Age = age
+					logger.Info("Возраст: %d", age)
+				}()
+			}
+			if settings.Gender {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					gender, err := getGender(ctx, r.Name, settings)
+					if err != nil {
+						logger.Error("не удалось выполнить запрос ", err)
+					}
+					r.Gender = gender
+					logger.Info("Пол: %s", gender)
+				}()
+			}
+			if settings.Nationality {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					nationalities, err := getNationalities(ctx, r.Name, settings)
+					if err != nil {
+						logger.Error("не удалось выполнить запрос ", err)
+					}
+					r.Nationality = nationalities
+					logger.Info("Национальность: %s", nationalities)
+				}()
+			}
 
 			wg.Wait()
 
 			// сохраняем обогащенные данные в базу
-			_, err = db.SaveDataToDatabase(r)
+			_, err = db.SaveDataToDatabase(ctx, r)
 			if err != nil {
 				logger.Error("не получилось сохранить данные в базу данных", err)
+				metrics.ObserveMessage(metrics.OutcomeError, time.Since(start))
+				tracing.End(span, err)
 				return err
 			}
+			metrics.ObserveMessage(metrics.OutcomeSuccess, time.Since(start))
 		}
 
 		// Подтверждение сообщения как обработанного.
-		err = c.Reader.CommitMessages(context.Background(), msg)
+		err = c.Reader.CommitMessages(ctx, msg)
+		tracing.End(span, err)
 		return err
 	}
 }
 
-func Start(brokers []string, topic, topicErr, groupID, connstr string) error {
-	// Инициализация клиента Kafka.
-	kfk, err := New(brokers, topic, topicErr, groupID)
-	if err != nil {
-		logger.Error("не удалось запустить сервис", err)
-		return err
-	}
-
-	// Инициализация базы данных.
-	db, err := postgres.New(connstr)
-	if err != nil {
-		logger.Error("нет соединения с PostgresSQL", err)
-		return err
-	}
-
+// Start запускает потребителя Kafka, сохраняющего данные в переданную базу данных.
+func Start(kfk *Client, db storage.Database) error {
 	// чтение следующего сообщения.
 	go func() {
 		for {
-			err = kfk.fetchProcessCommit(db)
+			err := kfk.fetchProcessCommit(db)
 			if err != nil {
 				logger.Error("не удалось прочитать сообщение", err)
 			}
 		}
 	}()
 
+	// сопоставление отказов из FIO_FAILED с задачами импорта.
+	go func() {
+		for {
+			err := kfk.fetchRejection(db)
+			if err != nil {
+				logger.Error("не удалось прочитать сообщение об отказе", err)
+			}
+		}
+	}()
+
 	// Ожидаем завершения работы программы.
 	select {}
 
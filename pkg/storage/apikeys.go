@@ -0,0 +1,16 @@
+package storage
+
+import "time"
+
+// APIKey Ключ доступа к API. Сам ключ не хранится, только его хеш.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Role       string     `json:"role"`
+	TenantID   string     `json:"tenant_id"`
+	Key        string     `json:"key,omitempty"` // заполняется только при создании
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Источники изменений записей.
+const (
+	SourceREST  = "rest"  // HTTP-обработчики
+	SourceGRPC  = "grpc"  // сервер gRPC
+	SourceKafka = "kafka" // потребитель Kafka
+	SourceJob   = "job"   // фоновые задачи
+)
+
+// Операции в истории изменений.
+const (
+	OpInsert  = "insert"
+	OpUpdate  = "update"
+	OpDelete  = "delete"
+	OpRestore = "restore"
+	OpPurge   = "purge"
+)
+
+// Audit Кто и откуда изменяет данные.
+type Audit struct {
+	Source string
+	Actor  string
+	Role   string // роль автора, если он аутентифицирован
+}
+
+type auditKey struct{}
+
+// WithAudit возвращает контекст с информацией об источнике и авторе изменений.
+func WithAudit(ctx context.Context, a Audit) context.Context {
+	return context.WithValue(ctx, auditKey{}, a)
+}
+
+// AuditFrom извлекает информацию об источнике изменений из контекста.
+func AuditFrom(ctx context.Context) Audit {
+	a, _ := ctx.Value(auditKey{}).(Audit)
+	return a
+}
+
+// HistoryEntry Запись истории изменений человека.
+type HistoryEntry struct {
+	ID        int64           `json:"id"`
+	PersonID  int             `json:"person_id"`
+	TenantID  string          `json:"tenant_id"`
+	Operation string          `json:"operation"`
+	OldValues json.RawMessage `json:"old_values,omitempty"`
+	NewValues json.RawMessage `json:"new_values,omitempty"`
+	Source    string          `json:"source"`
+	Actor     string          `json:"actor"`
+	ActorRole string          `json:"actor_role"`
+	ChangedAt time.Time       `json:"changed_at"`
+}
@@ -0,0 +1,31 @@
+package storage
+
+import "fmt"
+
+// PatchItem Элемент пакетного частичного обновления.
+type PatchItem struct {
+	ID      int
+	Version int // ожидаемая версия записи, 0 отключает проверку
+	Data    map[string]interface{}
+}
+
+// BatchResult Результат обработки одного элемента пакета.
+type BatchResult struct {
+	ID      int
+	Version int
+	Err     error
+}
+
+// BatchError Ошибка элемента пакета, из-за которой откатывается вся транзакция.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("элемент %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
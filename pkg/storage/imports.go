@@ -0,0 +1,42 @@
+package storage
+
+import "time"
+
+// Состояния задачи импорта.
+const (
+	ImportRunning   = "running"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+)
+
+// Этапы, на которых строка импорта может быть отклонена.
+const (
+	StageParse      = "parse"      // строка файла не разобрана
+	StagePublish    = "publish"    // сообщение не отправлено в Kafka
+	StageValidation = "validation" // сообщение отклонено потребителем и отправлено в FIO_FAILED
+)
+
+// ImportJob Задача импорта файла с ФИО в топик Kafka.
+type ImportJob struct {
+	ID         string     `json:"id"`
+	Status     string     `json:"status"`
+	Format     string     `json:"format"`
+	TotalRows  int        `json:"total_rows"`
+	Published  int        `json:"published"`
+	Rejected   int        `json:"rejected"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	Rejections []ImportRejection `json:"rejections"`
+}
+
+// ImportRejection Отклонённая строка импорта.
+type ImportRejection struct {
+	Row        int    `json:"row"`
+	Stage      string `json:"stage"`
+	Name       string `json:"name"`
+	Surname    string `json:"surname"`
+	Patronymic string `json:"patronymic"`
+	Reason     string `json:"reason"`
+}
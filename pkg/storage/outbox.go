@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Типы событий изменения записей.
+const (
+	EventPersonCreated = "person.created"
+	EventPersonUpdated = "person.updated"
+	EventPersonDeleted = "person.deleted"
+	EventPersonPurged  = "person.purged" // только в потоке изменений, в Kafka не публикуется
+)
+
+// EventTypes Типы событий, соответствующие операциям истории изменений.
+// Окончательное удаление (OpPurge) события не создаёт: о мягком удалении уже сообщено.
+var EventTypes = map[string]string{
+	OpInsert:  EventPersonCreated,
+	OpUpdate:  EventPersonUpdated,
+	OpRestore: EventPersonUpdated,
+	OpDelete:  EventPersonDeleted,
+}
+
+// OutboxEvent Событие изменения записи, ожидающее публикации.
+type OutboxEvent struct {
+	ID         int64           `json:"id"`
+	Type       string          `json:"type"`
+	TenantID   string          `json:"tenant_id"`
+	PersonID   int             `json:"person_id"`
+	Person     json.RawMessage `json:"person"`
+	Source     string          `json:"source"`
+	Actor      string          `json:"actor"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
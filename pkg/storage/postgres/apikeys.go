@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v4"
+	"github.com/zatrasz75/Service/pkg/storage"
+)
+
+// apiKeyColumns столбцы api_keys в порядке сканирования scanAPIKey.
+const apiKeyColumns = "id, name, prefix, role, tenant_id, created_at, last_used_at, revoked_at"
+
+func scanAPIKey(row pgx.Row) (storage.APIKey, error) {
+	var k storage.APIKey
+	err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.Role, &k.TenantID, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return k, storage.ErrNotFound
+	}
+	return k, err
+}
+
+// CreateAPIKey сохраняет хеш нового ключа доступа арендатора с ролью role.
+func (s *Store) CreateAPIKey(ctx context.Context, name, role, prefix, hash string) (storage.APIKey, error) {
+	return scanAPIKey(s.db.QueryRow(ctx, `
+		INSERT INTO api_keys (name, role, prefix, key_hash, tenant_id) VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+apiKeyColumns+`;`, name, role, prefix, hash, storage.TenantFrom(ctx)))
+}
+
+// ListAPIKeys возвращает все ключи доступа арендатора, включая отозванные.
+func (s *Store) ListAPIKeys(ctx context.Context) ([]storage.APIKey, error) {
+	rows, err := s.db.Query(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE tenant_id = $1 ORDER BY id;",
+		storage.TenantFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []storage.APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, k)
+	}
+
+	return result, rows.Err()
+}
+
+// RevokeAPIKey отзывает ключ доступа арендатора.
+func (s *Store) RevokeAPIKey(ctx context.Context, id int64) error {
+	tag, err := s.db.Exec(ctx, "UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND tenant_id = $2 AND revoked_at IS NULL;",
+		id, storage.TenantFrom(ctx))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// APIKeyByHash возвращает действующий ключ доступа любого арендатора по хешу и отмечает время
+// его использования не чаще раза в минуту. Отозванный или неизвестный ключ даёт storage.ErrNotFound.
+func (s *Store) APIKeyByHash(ctx context.Context, hash string) (storage.APIKey, error) {
+	k, err := scanAPIKey(s.db.QueryRow(ctx, `
+		SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL;`, hash))
+	if err != nil {
+		return k, err
+	}
+
+	_, err = s.db.Exec(ctx, `
+		UPDATE api_keys SET last_used_at = now()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute');`, k.ID)
+
+	return k, err
+}
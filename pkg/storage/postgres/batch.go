@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jackc/pgx/v4"
+	"github.com/zatrasz75/Service/pkg/storage"
+)
+
+// runBatch выполняет fn для каждого из n элементов в одной транзакции.
+// В режиме atomic первая ошибка откатывает всю транзакцию, иначе каждый элемент
+// выполняется в своей точке сохранения и его ошибка записывается в результат.
+func (s *Store) runBatch(ctx context.Context, n int, atomic bool, fn func(tx pgx.Tx, i int) (storage.BatchResult, error)) ([]storage.BatchResult, error) {
+	results := make([]storage.BatchResult, n)
+
+	err := s.inTx(ctx, func(tx pgx.Tx) error {
+		for i := 0; i < n; i++ {
+			if atomic {
+				res, err := fn(tx, i)
+				if err != nil {
+					return &storage.BatchError{Index: i, Err: err}
+				}
+				results[i] = res
+				continue
+			}
+
+			// Точка сохранения изолирует ошибку элемента от остальных.
+			sp, err := tx.Begin(ctx)
+			if err != nil {
+				return err
+			}
+			res, err := fn(sp, i)
+			if err != nil {
+				if rbErr := sp.Rollback(ctx); rbErr != nil {
+					return rbErr
+				}
+				res.Err = err
+				results[i] = res
+				continue
+			}
+			if err = sp.Commit(ctx); err != nil {
+				return err
+			}
+			results[i] = res
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// SaveDataBatch сохраняет пакет записей.
+func (s *Store) SaveDataBatch(ctx context.Context, items []storage.Data, atomic bool) ([]storage.BatchResult, error) {
+	return s.runBatch(ctx, len(items), atomic, func(tx pgx.Tx, i int) (storage.BatchResult, error) {
+		id, err := insertData(ctx, tx, items[i])
+		if err != nil {
+			return storage.BatchResult{}, err
+		}
+		return storage.BatchResult{ID: id, Version: 1}, nil
+	})
+}
+
+// PartialUpdateBatch частично обновляет пакет записей.
+func (s *Store) PartialUpdateBatch(ctx context.Context, items []storage.PatchItem, atomic bool) ([]storage.BatchResult, error) {
+	return s.runBatch(ctx, len(items), atomic, func(tx pgx.Tx, i int) (storage.BatchResult, error) {
+		version, err := partialUpdate(ctx, tx, items[i].ID, items[i].Data, items[i].Version)
+		return storage.BatchResult{ID: items[i].ID, Version: version}, err
+	})
+}
+
+// DeleteBatch помечает пакет записей как удалённые.
+func (s *Store) DeleteBatch(ctx context.Context, ids []int, atomic bool) ([]storage.BatchResult, error) {
+	return s.runBatch(ctx, len(ids), atomic, func(tx pgx.Tx, i int) (storage.BatchResult, error) {
+		return storage.BatchResult{ID: ids[i]}, softDelete(ctx, tx, ids[i])
+	})
+}
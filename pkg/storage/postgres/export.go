@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jackc/pgx/v4"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"strconv"
+)
+
+// exportFetchSize количество строк, читаемых из курсора за один запрос.
+const exportFetchSize = 1000
+
+// Export передаёт в fn все записи, подходящие под фильтр, в порядке id.
+// Строки читаются из серверного курсора порциями, поэтому потребление памяти
+// не зависит от размера выборки.
+func (s *Store) Export(ctx context.Context, filter storage.Filter, fn func(storage.UsersData) error) error {
+	where, args := filterClause(ctx, filter, nil)
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "DECLARE export_cursor NO SCROLL CURSOR FOR SELECT "+usersDataColumns+
+		" FROM service_data WHERE "+where+" ORDER BY id", args...)
+	if err != nil {
+		return err
+	}
+
+	fetch := "FETCH " + strconv.Itoa(exportFetchSize) + " FROM export_cursor"
+	for {
+		n, err := fetchBatch(ctx, tx, fetch, fn)
+		if err != nil {
+			return err
+		}
+		if n < exportFetchSize {
+			break
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Count возвращает количество записей арендатора, подходящих под фильтр.
+func (s *Store) Count(ctx context.Context, filter storage.Filter) (int, error) {
+	where, args := filterClause(ctx, filter, nil)
+
+	var n int
+	err := s.db.QueryRow(ctx, "SELECT count(*) FROM service_data WHERE "+where, args...).Scan(&n)
+
+	return n, err
+}
+
+// fetchBatch читает одну порцию строк из курсора и возвращает их количество.
+func fetchBatch(ctx context.Context, tx pgx.Tx, fetch string, fn func(storage.UsersData) error) (int, error) {
+	rows, err := tx.Query(ctx, fetch)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		data, err := scanUsersData(rows)
+		if err != nil {
+			return n, err
+		}
+		if err = fn(data); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, rows.Err()
+}
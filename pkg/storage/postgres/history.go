@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v4"
+	"github.com/zatrasz75/Service/pkg/storage"
+)
+
+// historyColumns столбцы service_data_history в порядке сканирования queryHistory.
+const historyColumns = "id, person_id, tenant_id, operation, old_values, new_values, source, actor, actor_role, changed_at"
+
+// rowJSON выражение, преобразующее строку service_data в JSON для истории изменений.
+// Служебные столбцы поиска исключаются.
+const rowJSON = "(to_jsonb(service_data.*) - 'full_name' - 'search_vector')"
+
+// snapshot возвращает текущее состояние записи в виде JSON и блокирует её до конца транзакции.
+// Если запись не найдена среди записей арендатора из контекста, возвращает storage.ErrNotFound.
+// Последующие изменения записи по id выполняются только после успешного snapshot.
+func snapshot(ctx context.Context, tx pgx.Tx, id int, deleted bool) ([]byte, error) {
+	query := "SELECT " + rowJSON + " FROM service_data WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL FOR UPDATE"
+	if deleted {
+		query = "SELECT " + rowJSON + " FROM service_data WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NOT NULL FOR UPDATE"
+	}
+
+	var data []byte
+	err := tx.QueryRow(ctx, query, id, storage.TenantFrom(ctx)).Scan(&data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+
+	return data, err
+}
+
+// recordHistory добавляет запись в историю изменений и событие в outbox в рамках транзакции tx.
+func recordHistory(ctx context.Context, tx pgx.Tx, personID int, operation string, oldValues, newValues []byte) error {
+	a := storage.AuditFrom(ctx)
+	_, err := tx.Exec(ctx, `
+		INSERT INTO service_data_history (person_id, tenant_id, operation, old_values, new_values, source, actor, actor_role)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8);`,
+		personID, storage.TenantFrom(ctx), operation, oldValues, newValues, a.Source, a.Actor, a.Role)
+	if err != nil {
+		return err
+	}
+
+	return recordOutbox(ctx, tx, personID, operation, oldValues, newValues)
+}
+
+// inTx выполняет fn в транзакции.
+func (s *Store) inTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// History возвращает историю изменений записи арендатора в порядке их выполнения.
+func (s *Store) History(ctx context.Context, id int) ([]storage.HistoryEntry, error) {
+	return s.queryHistory(ctx, `
+		SELECT `+historyColumns+`
+		FROM service_data_history WHERE person_id = $1 AND tenant_id = $2 ORDER BY id;`, id, storage.TenantFrom(ctx))
+}
+
+// committed условие записей истории из транзакций, завершившихся до начала всех выполняющихся.
+// Транзакции, которые зафиксируются позже, получат записи с большим tx_id, поэтому порядок
+// (tx_id, id) не нарушается. Долгая транзакция задерживает поток изменений до своего завершения.
+const committed = "tx_id < pg_snapshot_xmin(pg_current_snapshot())"
+
+// Changes возвращает не более limit записей истории арендатора, следующих за записью afterID
+// в порядке фиксации транзакций. Записи незавершённых транзакций не возвращаются.
+func (s *Store) Changes(ctx context.Context, afterID int64, limit int) ([]storage.HistoryEntry, error) {
+	return s.queryHistory(ctx, `
+		WITH cursor AS (
+			SELECT COALESCE((SELECT tx_id FROM service_data_history WHERE id <= $2 ORDER BY id DESC LIMIT 1), '0'::xid8) AS tx_id
+		)
+		SELECT `+historyColumns+`
+		FROM service_data_history
+		WHERE tenant_id = $1 AND `+committed+` AND (tx_id, id) > ((SELECT tx_id FROM cursor), $2)
+		ORDER BY tx_id, id LIMIT $3;`, storage.TenantFrom(ctx), afterID, limit)
+}
+
+// LastChangeID возвращает идентификатор последней записи истории арендатора из завершённых
+// транзакций или 0, если история пуста.
+func (s *Store) LastChangeID(ctx context.Context) (int64, error) {
+	var id int64
+	err := s.db.QueryRow(ctx, `
+		SELECT COALESCE((SELECT id FROM service_data_history WHERE tenant_id = $1 AND `+committed+`
+			ORDER BY tx_id DESC, id DESC LIMIT 1), 0);`, storage.TenantFrom(ctx)).Scan(&id)
+	return id, err
+}
+
+// changesChannel канал уведомлений о новых записях истории изменений.
+const changesChannel = "people_changes"
+
+// ListenChanges вызывает notify после фиксации каждой транзакции, добавившей записи в историю изменений.
+// Занимает одно соединение пула до отмены ctx или ошибки соединения.
+func (s *Store) ListenChanges(ctx context.Context, notify func()) error {
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err = conn.Exec(ctx, "LISTEN "+changesChannel); err != nil {
+		return err
+	}
+	defer conn.Exec(context.Background(), "UNLISTEN "+changesChannel)
+
+	for {
+		if _, err = conn.Conn().WaitForNotification(ctx); err != nil {
+			return err
+		}
+		notify()
+	}
+}
+
+// queryHistory выполняет запрос к истории изменений и читает результат.
+func (s *Store) queryHistory(ctx context.Context, query string, args ...interface{}) ([]storage.HistoryEntry, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []storage.HistoryEntry
+	for rows.Next() {
+		var h storage.HistoryEntry
+		var oldValues, newValues []byte
+		if err = rows.Scan(&h.ID, &h.PersonID, &h.TenantID, &h.Operation, &oldValues, &newValues, &h.Source, &h.Actor, &h.ActorRole, &h.ChangedAt); err != nil {
+			return nil, err
+		}
+		h.OldValues = oldValues
+		h.NewValues = newValues
+		result = append(result, h)
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v4"
+	"github.com/zatrasz75/Service/pkg/storage"
+)
+
+// CreateImportJob создаёт задачу импорта арендатора в состоянии running.
+func (s *Store) CreateImportJob(ctx context.Context, id, format string) error {
+	_, err := s.db.Exec(ctx, "INSERT INTO import_jobs (id, status, format, tenant_id) VALUES ($1, $2, $3, $4)",
+		id, storage.ImportRunning, format, storage.TenantFrom(ctx))
+	return err
+}
+
+// UpdateImportJob обновляет прогресс задачи импорта арендатора.
+func (s *Store) UpdateImportJob(ctx context.Context, id string, totalRows, published int) error {
+	_, err := s.db.Exec(ctx, "UPDATE import_jobs SET total_rows = $2, published = $3 WHERE id = $1 AND tenant_id = $4",
+		id, totalRows, published, storage.TenantFrom(ctx))
+	return err
+}
+
+// FinishImportJob переводит задачу импорта арендатора в конечное состояние.
+func (s *Store) FinishImportJob(ctx context.Context, id, status, errMsg string) error {
+	_, err := s.db.Exec(ctx, "UPDATE import_jobs SET status = $2, error = $3, finished_at = now() WHERE id = $1 AND tenant_id = $4",
+		id, status, errMsg, storage.TenantFrom(ctx))
+	return err
+}
+
+// AddImportRejection сохраняет отклонённую строку задачи импорта арендатора.
+// Отказ по задаче другого арендатора и повторная доставка отказа по той же строке игнорируются.
+func (s *Store) AddImportRejection(ctx context.Context, jobID string, rej storage.ImportRejection) error {
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO import_rejections (job_id, row_number, stage, name, surname, patronymic, reason)
+		SELECT $1::varchar, $2::int, $3::varchar, $4::varchar, $5::varchar, $6::varchar, $7::text
+		WHERE EXISTS (SELECT 1 FROM import_jobs WHERE id = $1 AND tenant_id = $8)
+		ON CONFLICT (job_id, row_number) DO NOTHING;`,
+		jobID, rej.Row, rej.Stage, rej.Name, rej.Surname, rej.Patronymic, rej.Reason, storage.TenantFrom(ctx))
+	return err
+}
+
+// GetImportJob возвращает задачу импорта арендатора вместе с отклонёнными строками.
+func (s *Store) GetImportJob(ctx context.Context, id string) (storage.ImportJob, error) {
+	var job storage.ImportJob
+	err := s.db.QueryRow(ctx, `
+		SELECT id, status, format, total_rows, published, error, created_at, finished_at
+		FROM import_jobs WHERE id = $1 AND tenant_id = $2;`, id, storage.TenantFrom(ctx),
+	).Scan(&job.ID, &job.Status, &job.Format, &job.TotalRows, &job.Published, &job.Error, &job.CreatedAt, &job.FinishedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return job, storage.ErrNotFound
+	}
+	if err != nil {
+		return job, err
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT row_number, stage, name, surname, patronymic, reason
+		FROM import_rejections WHERE job_id = $1 ORDER BY row_number;`, id)
+	if err != nil {
+		return job, err
+	}
+	defer rows.Close()
+
+	job.Rejections = []storage.ImportRejection{}
+	for rows.Next() {
+		var rej storage.ImportRejection
+		if err = rows.Scan(&rej.Row, &rej.Stage, &rej.Name, &rej.Surname, &rej.Patronymic, &rej.Reason); err != nil {
+			return job, err
+		}
+		job.Rejections = append(job.Rejections, rej)
+	}
+	job.Rejected = len(job.Rejections)
+
+	return job, rows.Err()
+}
@@ -0,0 +1,376 @@
+package postgres
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/zatrasz75/Service/pkg/logger"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// migrationLockKey ключ рекомендательной блокировки, под которой выполняются миграции.
+// Позволяет нескольким репликам стартовать одновременно.
+const migrationLockKey = 7_519_220_001
+
+// Шаблон имени файла миграции: 0001_name.up.sql или 0001_name.down.sql.
+var migrationFileRegex = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// ErrDirtyMigration возвращается, если предыдущая миграция завершилась с ошибкой.
+var ErrDirtyMigration = errors.New("база данных в состоянии dirty: исправьте схему и выполните migrate force")
+
+// Migration Версионированная миграция схемы.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus Состояние миграции в базе данных.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	Dirty     bool
+	AppliedAt *time.Time
+}
+
+// loadMigrations читает встроенные SQL-файлы и упорядочивает их по версии.
+func loadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationsFS, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		m := migrationFileRegex.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		version, err := strconv.Atoi(m[1])
+		if err != nil {
+			return nil, err
+		}
+		body, err := migrationsFS.ReadFile("migrations/" + e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	result := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("миграция %d: отсутствует up-файл", mig.Version)
+		}
+		result = append(result, *mig)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+
+	return result, nil
+}
+
+// withMigrationLock выполняет fn на отдельном соединении под рекомендательной блокировкой.
+// Миграции изменяют данные всех арендаторов.
+func (s *Store) withMigrationLock(ctx context.Context, fn func(conn *pgx.Conn) error) error {
+	ctx = storage.WithTenant(ctx, storage.AllTenants)
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err = conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return err
+	}
+	defer func() {
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey); err != nil {
+			logger.Error("не удалось снять блокировку миграций", err)
+		}
+	}()
+
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT PRIMARY KEY,
+    dirty BOOLEAN NOT NULL DEFAULT false,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);`)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn.Conn())
+}
+
+// appliedMigrations возвращает применённые версии и признак dirty для каждой из них.
+func appliedMigrations(ctx context.Context, conn *pgx.Conn) (map[int]bool, error) {
+	rows, err := conn.Query(ctx, "SELECT version, dirty FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		var dirty bool
+		if err = rows.Scan(&version, &dirty); err != nil {
+			return nil, err
+		}
+		applied[version] = dirty
+	}
+
+	return applied, rows.Err()
+}
+
+// checkDirty возвращает ErrDirtyMigration, если хотя бы одна версия помечена как dirty.
+func checkDirty(applied map[int]bool) error {
+	for version, dirty := range applied {
+		if dirty {
+			return fmt.Errorf("версия %d: %w", version, ErrDirtyMigration)
+		}
+	}
+	return nil
+}
+
+// runMigration выполняет SQL миграции в транзакции и фиксирует результат в schema_migrations.
+// При ошибке версия помечается как dirty.
+func runMigration(ctx context.Context, conn *pgx.Conn, version int, sql string, up bool) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, sql); err != nil {
+		// Отметка записывается вне прерванной транзакции, иначе она будет отменена вместе с ней.
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			logger.Error("не удалось откатить транзакцию миграции", rbErr)
+		}
+		_, markErr := conn.Exec(ctx, `
+			INSERT INTO schema_migrations (version, dirty) VALUES ($1, true)
+			ON CONFLICT (version) DO UPDATE SET dirty = true;`, version)
+		if markErr != nil {
+			logger.Error("не удалось пометить миграцию как dirty", markErr)
+		}
+		return fmt.Errorf("миграция %d: %w", version, err)
+	}
+
+	if up {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO schema_migrations (version, dirty, applied_at) VALUES ($1, false, now())
+			ON CONFLICT (version) DO UPDATE SET dirty = false, applied_at = now();`, version)
+	} else {
+		_, err = tx.Exec(ctx, "DELETE FROM schema_migrations WHERE version = $1", version)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// MigrateUp применяет все неприменённые миграции.
+func (s *Store) MigrateUp(ctx context.Context) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	return s.withMigrationLock(ctx, func(conn *pgx.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err = checkDirty(applied); err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			if err = runMigration(ctx, conn, m.Version, m.Up, true); err != nil {
+				return err
+			}
+			logger.Info("Применена миграция %04d_%s", m.Version, m.Name)
+		}
+		return nil
+	})
+}
+
+// MigrateDown откатывает steps последних применённых миграций.
+func (s *Store) MigrateDown(ctx context.Context, steps int) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	return s.withMigrationLock(ctx, func(conn *pgx.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err = checkDirty(applied); err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+			m := migrations[i]
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+			if m.Down == "" {
+				return fmt.Errorf("миграция %d: отсутствует down-файл", m.Version)
+			}
+			if err = runMigration(ctx, conn, m.Version, m.Down, false); err != nil {
+				return err
+			}
+			logger.Info("Откачена миграция %04d_%s", m.Version, m.Name)
+			steps--
+		}
+		return nil
+	})
+}
+
+// ForceVersion устанавливает состояние schema_migrations в указанную версию без выполнения SQL.
+// Используется для ручного восстановления после неудачной миграции.
+func (s *Store) ForceVersion(ctx context.Context, version int) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	return s.withMigrationLock(ctx, func(conn *pgx.Conn) error {
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+
+		if _, err = tx.Exec(ctx, "DELETE FROM schema_migrations"); err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			if m.Version > version {
+				break
+			}
+			if _, err = tx.Exec(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)", m.Version); err != nil {
+				return err
+			}
+		}
+
+		return tx.Commit(ctx)
+	})
+}
+
+// MigrationsStatus возвращает состояние всех известных миграций.
+func (s *Store) MigrationsStatus(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []MigrationStatus
+	err = s.withMigrationLock(ctx, func(conn *pgx.Conn) error {
+		rows, err := conn.Query(ctx, "SELECT version, dirty, applied_at FROM schema_migrations")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		type state struct {
+			dirty     bool
+			appliedAt time.Time
+		}
+		applied := make(map[int]state)
+		for rows.Next() {
+			var version int
+			var st state
+			if err = rows.Scan(&version, &st.dirty, &st.appliedAt); err != nil {
+				return err
+			}
+			applied[version] = st
+		}
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			ms := MigrationStatus{Version: m.Version, Name: m.Name}
+			if st, ok := applied[m.Version]; ok {
+				at := st.appliedAt
+				ms.Applied = true
+				ms.Dirty = st.dirty
+				ms.AppliedAt = &at
+			}
+			result = append(result, ms)
+		}
+		return nil
+	})
+
+	return result, err
+}
+
+// CheckMigrations проверяет, что все встроенные миграции применены и ни одна не помечена как dirty.
+// В отличие от MigrationsStatus не ждёт рекомендательной блокировки.
+func (s *Store) CheckMigrations(ctx context.Context) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.db.Query(ctx, "SELECT version, dirty FROM schema_migrations")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		var dirty bool
+		if err = rows.Scan(&version, &dirty); err != nil {
+			return err
+		}
+		applied[version] = dirty
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	if err = checkDirty(applied); err != nil {
+		return err
+	}
+
+	var pending []string
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; !ok {
+			pending = append(pending, strconv.Itoa(m.Version))
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("не применены миграции: %s", strings.Join(pending, ", "))
+	}
+	return nil
+}
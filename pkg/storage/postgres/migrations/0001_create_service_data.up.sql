@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS service_data (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255),
+    surname VARCHAR(255),
+    patronymic VARCHAR(255),
+    age INT,
+    gender VARCHAR(255),
+    nationality VARCHAR(255)
+);
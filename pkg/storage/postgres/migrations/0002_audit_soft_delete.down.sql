@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS service_data_updated_at ON service_data;
+DROP FUNCTION IF EXISTS service_data_set_updated_at();
+DROP INDEX IF EXISTS service_data_deleted_at_idx;
+
+ALTER TABLE service_data
+    DROP COLUMN IF EXISTS deleted_at,
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS created_at;
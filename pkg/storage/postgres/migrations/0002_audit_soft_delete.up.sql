@@ -0,0 +1,19 @@
+ALTER TABLE service_data
+    ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS service_data_deleted_at_idx ON service_data (deleted_at);
+
+CREATE OR REPLACE FUNCTION service_data_set_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = now();
+    NEW.created_at = OLD.created_at;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS service_data_updated_at ON service_data;
+CREATE TRIGGER service_data_updated_at
+    BEFORE UPDATE ON service_data
+    FOR EACH ROW EXECUTE FUNCTION service_data_set_updated_at();
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS service_data_history;
+DROP FUNCTION IF EXISTS service_data_history_append_only();
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS service_data_history (
+    id BIGSERIAL PRIMARY KEY,
+    person_id INT NOT NULL,
+    operation VARCHAR(32) NOT NULL,
+    old_values JSONB,
+    new_values JSONB,
+    source VARCHAR(64) NOT NULL,
+    actor VARCHAR(255) NOT NULL DEFAULT '',
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS service_data_history_person_idx ON service_data_history (person_id, id);
+
+-- История только дополняется: изменение и удаление записей запрещены.
+CREATE OR REPLACE FUNCTION service_data_history_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'service_data_history is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS service_data_history_append_only ON service_data_history;
+CREATE TRIGGER service_data_history_append_only
+    BEFORE UPDATE OR DELETE ON service_data_history
+    FOR EACH ROW EXECUTE FUNCTION service_data_history_append_only();
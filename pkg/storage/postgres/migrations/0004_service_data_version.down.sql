@@ -0,0 +1,9 @@
+CREATE OR REPLACE FUNCTION service_data_set_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = now();
+    NEW.created_at = OLD.created_at;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE service_data DROP COLUMN IF EXISTS version;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id VARCHAR(32) PRIMARY KEY,
+    status VARCHAR(16) NOT NULL,
+    format VARCHAR(16) NOT NULL,
+    total_rows INT NOT NULL DEFAULT 0,
+    published INT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS import_rejections (
+    id BIGSERIAL PRIMARY KEY,
+    job_id VARCHAR(32) NOT NULL REFERENCES import_jobs (id) ON DELETE CASCADE,
+    row_number INT NOT NULL,
+    stage VARCHAR(16) NOT NULL,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    surname VARCHAR(255) NOT NULL DEFAULT '',
+    patronymic VARCHAR(255) NOT NULL DEFAULT '',
+    reason TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (job_id, row_number)
+);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS service_data_full_name_trgm_idx;
+DROP INDEX IF EXISTS service_data_search_vector_idx;
+
+ALTER TABLE service_data
+    DROP COLUMN IF EXISTS search_vector,
+    DROP COLUMN IF EXISTS full_name;
@@ -0,0 +1,12 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE service_data
+    ADD COLUMN IF NOT EXISTS full_name TEXT GENERATED ALWAYS AS (
+        replace(lower(coalesce(name, '') || ' ' || coalesce(surname, '') || ' ' || coalesce(patronymic, '')), 'ё', 'е')
+    ) STORED,
+    ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+        to_tsvector('simple', coalesce(name, '') || ' ' || coalesce(surname, '') || ' ' || coalesce(patronymic, ''))
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS service_data_search_vector_idx ON service_data USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS service_data_full_name_trgm_idx ON service_data USING GIN (full_name gin_trgm_ops);
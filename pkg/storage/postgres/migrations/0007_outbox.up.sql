@@ -0,0 +1,11 @@
+-- События изменений записей для публикации в Kafka. Строки добавляются в той же
+-- транзакции, что и изменение service_data, и удаляются после публикации.
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    person_id INT NOT NULL,
+    event_type VARCHAR(32) NOT NULL,
+    payload JSONB NOT NULL,
+    source VARCHAR(64) NOT NULL,
+    actor VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
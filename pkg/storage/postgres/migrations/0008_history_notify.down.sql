@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS service_data_history_notify ON service_data_history;
+DROP FUNCTION IF EXISTS service_data_history_notify();
@@ -0,0 +1,13 @@
+-- Уведомление слушателей канала people_changes о новых записях истории изменений.
+-- Уведомление доставляется после фиксации транзакции.
+CREATE OR REPLACE FUNCTION service_data_history_notify() RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('people_changes', '');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS service_data_history_notify ON service_data_history;
+CREATE TRIGGER service_data_history_notify
+    AFTER INSERT ON service_data_history
+    FOR EACH STATEMENT EXECUTE FUNCTION service_data_history_notify();
@@ -0,0 +1,2 @@
+ALTER TABLE service_data_history DROP COLUMN IF EXISTS actor_role;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS role;
@@ -0,0 +1,6 @@
+-- Роль ключа доступа. Ключи, созданные до появления ролей, сохраняют полный доступ.
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'admin';
+ALTER TABLE api_keys ALTER COLUMN role DROP DEFAULT;
+
+-- Роль автора изменения в истории.
+ALTER TABLE service_data_history ADD COLUMN IF NOT EXISTS actor_role VARCHAR(16) NOT NULL DEFAULT '';
@@ -0,0 +1,21 @@
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY['service_data', 'service_data_history', 'outbox', 'webhooks', 'import_jobs', 'api_keys'] LOOP
+        EXECUTE format('DROP POLICY IF EXISTS tenant_isolation ON %I', t);
+        EXECUTE format('ALTER TABLE %I NO FORCE ROW LEVEL SECURITY', t);
+        EXECUTE format('ALTER TABLE %I DISABLE ROW LEVEL SECURITY', t);
+    END LOOP;
+END $$;
+
+DROP TABLE IF EXISTS tenants;
+
+ALTER TABLE api_keys DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE import_jobs DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE webhooks DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE outbox DROP COLUMN IF EXISTS tenant_id;
+DROP INDEX IF EXISTS service_data_history_tenant_idx;
+ALTER TABLE service_data_history DROP COLUMN IF EXISTS tenant_id;
+DROP INDEX IF EXISTS service_data_tenant_idx;
+ALTER TABLE service_data DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,45 @@
+-- Арендатор записей. Существующие данные принадлежат арендатору default.
+ALTER TABLE service_data ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+CREATE INDEX IF NOT EXISTS service_data_tenant_idx ON service_data (tenant_id, id);
+
+ALTER TABLE service_data_history ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+CREATE INDEX IF NOT EXISTS service_data_history_tenant_idx ON service_data_history (tenant_id, person_id, id);
+
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE import_jobs ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+-- Настройки обогащения арендаторов. Арендатор без строки использует значения по умолчанию.
+CREATE TABLE IF NOT EXISTS tenants (
+    id VARCHAR(64) PRIMARY KEY,
+    enrich_age BOOLEAN NOT NULL DEFAULT true,
+    enrich_gender BOOLEAN NOT NULL DEFAULT true,
+    enrich_nationality BOOLEAN NOT NULL DEFAULT true,
+    country_id VARCHAR(2) NOT NULL DEFAULT '',
+    enrichment_api_key TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Политики строк по арендатору. Действуют, когда приложение устанавливает app.tenant_id
+-- (DB_ROW_LEVEL_SECURITY=true); без установленного значения и со значением '*' видны все строки.
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY['service_data', 'service_data_history', 'outbox', 'webhooks', 'import_jobs', 'api_keys'] LOOP
+        EXECUTE format('ALTER TABLE %I ENABLE ROW LEVEL SECURITY', t);
+        EXECUTE format('ALTER TABLE %I FORCE ROW LEVEL SECURITY', t);
+        EXECUTE format('DROP POLICY IF EXISTS tenant_isolation ON %I', t);
+        EXECUTE format($p$CREATE POLICY tenant_isolation ON %I
+            USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', '*')
+                OR tenant_id = current_setting('app.tenant_id', true))$p$, t);
+    END LOOP;
+END $$;
+
+ALTER TABLE tenants ENABLE ROW LEVEL SECURITY;
+ALTER TABLE tenants FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON tenants;
+CREATE POLICY tenant_isolation ON tenants
+    USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', '*')
+        OR id = current_setting('app.tenant_id', true));
@@ -0,0 +1,16 @@
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY['service_data', 'service_data_history', 'outbox', 'webhooks', 'import_jobs', 'api_keys'] LOOP
+        EXECUTE format('DROP POLICY IF EXISTS tenant_isolation ON %I', t);
+        EXECUTE format($p$CREATE POLICY tenant_isolation ON %I
+            USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', '*')
+                OR tenant_id = current_setting('app.tenant_id', true))$p$, t);
+    END LOOP;
+END $$;
+
+DROP POLICY IF EXISTS tenant_isolation ON tenants;
+CREATE POLICY tenant_isolation ON tenants
+    USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', '*')
+        OR id = current_setting('app.tenant_id', true));
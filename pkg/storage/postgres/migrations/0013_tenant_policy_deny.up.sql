@@ -0,0 +1,16 @@
+-- Политики строк без app.tenant_id не пропускают ни одной строки. Все строки видны только
+-- со значением '*', которое приложение устанавливает для фоновых задач.
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY['service_data', 'service_data_history', 'outbox', 'webhooks', 'import_jobs', 'api_keys'] LOOP
+        EXECUTE format('DROP POLICY IF EXISTS tenant_isolation ON %I', t);
+        EXECUTE format($p$CREATE POLICY tenant_isolation ON %I
+            USING (current_setting('app.tenant_id', true) IN (tenant_id, '*'))$p$, t);
+    END LOOP;
+END $$;
+
+DROP POLICY IF EXISTS tenant_isolation ON tenants;
+CREATE POLICY tenant_isolation ON tenants
+    USING (current_setting('app.tenant_id', true) IN (id, '*'));
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS service_data_history_tenant_tx_idx;
+ALTER TABLE service_data_history DROP COLUMN IF EXISTS tx_id;
@@ -0,0 +1,5 @@
+-- Транзакция, добавившая запись истории. Поток изменений читает записи в порядке (tx_id, id)
+-- только из завершённых транзакций, поэтому запись с меньшим id, зафиксированная позже,
+-- не пропускается. Существующие записи получают идентификатор транзакции миграции.
+ALTER TABLE service_data_history ADD COLUMN IF NOT EXISTS tx_id xid8 NOT NULL DEFAULT pg_current_xact_id();
+CREATE INDEX IF NOT EXISTS service_data_history_tenant_tx_idx ON service_data_history (tenant_id, tx_id, id);
@@ -0,0 +1,160 @@
+package postgres
+
+import (
+	"regexp"
+	"strings"
+)
+
+// nonLetters всё, кроме букв, удаляется из поисковых слов.
+var nonLetters = regexp.MustCompile(`[^\p{L}]+`)
+
+// latinDigraphs сочетания латинских букв и соответствующие им русские буквы.
+// Порядок важен: более длинные сочетания заменяются первыми.
+var latinDigraphs = []struct{ lat, cyr string }{
+	{"shch", "щ"}, {"sch", "щ"}, {"zh", "ж"}, {"kh", "х"}, {"ts", "ц"}, {"ch", "ч"}, {"sh", "ш"},
+	{"yu", "ю"}, {"ya", "я"}, {"yo", "ё"}, {"ye", "е"}, {"iy", "ий"}, {"yi", "ый"},
+}
+
+// latinLetters однобуквенная транслитерация латиницы в кириллицу.
+var latinLetters = map[rune]string{
+	'a': "а", 'b': "б", 'c': "к", 'd': "д", 'e': "е", 'f': "ф", 'g': "г", 'h': "х", 'i': "и",
+	'j': "й", 'k': "к", 'l': "л", 'm': "м", 'n': "н", 'o': "о", 'p': "п", 'q': "к", 'r': "р",
+	's': "с", 't': "т", 'u': "у", 'v': "в", 'w': "в", 'x': "кс", 'y': "й", 'z': "з",
+}
+
+// diminutives уменьшительные формы русских имён и соответствующие полные имена.
+var diminutives = map[string][]string{
+	"саша":    {"александр", "александра"},
+	"шура":    {"александр", "александра"},
+	"женя":    {"евгений", "евгения"},
+	"валя":    {"валентин", "валентина"},
+	"дима":    {"дмитрий"},
+	"ваня":    {"иван"},
+	"петя":    {"пётр", "петр"},
+	"коля":    {"николай"},
+	"миша":    {"михаил"},
+	"лёша":    {"алексей"},
+	"алёша":   {"алексей"},
+	"серёжа":  {"сергей"},
+	"вова":    {"владимир"},
+	"володя":  {"владимир"},
+	"паша":    {"павел"},
+	"костя":   {"константин"},
+	"андрюша": {"андрей"},
+	"гриша":   {"григорий"},
+	"толя":    {"анатолий"},
+	"витя":    {"виктор"},
+	"юра":     {"юрий"},
+	"слава":   {"вячеслав", "владислав", "ярослав", "станислав"},
+	"стас":    {"станислав"},
+	"федя":    {"фёдор", "федор"},
+	"боря":    {"борис"},
+	"лёва":    {"лев"},
+	"катя":    {"екатерина"},
+	"маша":    {"мария"},
+	"наташа":  {"наталья", "наталия"},
+	"таня":    {"татьяна"},
+	"лена":    {"елена"},
+	"оля":     {"ольга"},
+	"аня":     {"анна"},
+	"юля":     {"юлия"},
+	"света":   {"светлана"},
+	"настя":   {"анастасия"},
+	"люба":    {"любовь"},
+	"надя":    {"надежда"},
+	"ира":     {"ирина"},
+	"галя":    {"галина"},
+	"люда":    {"людмила"},
+	"даша":    {"дарья"},
+	"ксюша":   {"ксения"},
+	"соня":    {"софья", "софия"},
+}
+
+// plainDiminutives уменьшительные формы из diminutives, записанные через е вместо ё:
+// запрос «алеша» находит «алексей» так же, как «алёша».
+var plainDiminutives = func() map[string][]string {
+	m := make(map[string][]string, len(diminutives))
+	for short, full := range diminutives {
+		key := strings.ReplaceAll(short, "ё", "е")
+		m[key] = append(m[key], full...)
+	}
+	return m
+}()
+
+// transliterate переводит латинское написание имени в кириллицу.
+func transliterate(word string) string {
+	for _, d := range latinDigraphs {
+		word = strings.ReplaceAll(word, d.lat, d.cyr)
+	}
+
+	var b strings.Builder
+	for _, r := range word {
+		if cyr, ok := latinLetters[r]; ok {
+			b.WriteString(cyr)
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// hasLatin сообщает, содержит ли слово латинские буквы.
+func hasLatin(word string) bool {
+	for _, r := range word {
+		if r >= 'a' && r <= 'z' {
+			return true
+		}
+	}
+	return false
+}
+
+// searchTerms разбивает поисковый запрос на слова в нижнем регистре.
+func searchTerms(query string) []string {
+	var terms []string
+	for _, w := range nonLetters.Split(strings.ToLower(query), -1) {
+		if w != "" {
+			terms = append(terms, w)
+		}
+	}
+	return terms
+}
+
+// nameVariants возвращает варианты написания слова: транслитерацию, замену ё на е
+// и полные формы уменьшительных имён.
+func nameVariants(word string) []string {
+	seen := map[string]bool{}
+	var result []string
+	add := func(v string) {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+
+	add(word)
+	if hasLatin(word) {
+		word = transliterate(word)
+		add(word)
+	}
+	plain := strings.ReplaceAll(word, "ё", "е")
+	add(plain)
+	for _, full := range plainDiminutives[plain] {
+		add(full)
+		add(strings.ReplaceAll(full, "ё", "е"))
+	}
+
+	return result
+}
+
+// normalizeQuery возвращает запрос для сравнения по триграммам: слова в кириллице без ё.
+func normalizeQuery(terms []string) string {
+	words := make([]string, len(terms))
+	for i, t := range terms {
+		if hasLatin(t) {
+			t = transliterate(t)
+		}
+		words[i] = strings.ReplaceAll(t, "ё", "е")
+	}
+	return strings.Join(words, " ")
+}
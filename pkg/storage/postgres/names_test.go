@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTransliterate(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"ivan", "иван"},
+		{"ivanov", "иванов"},
+		{"zhukov", "жуков"},
+		{"khabibulin", "хабибулин"},
+		{"tsoy", "цой"},
+		{"chekhov", "чехов"},
+		{"shukshin", "шукшин"},
+		{"shchukin", "щукин"},
+		{"schukin", "щукин"},
+		{"yuriy", "юрий"},
+		{"yakovlev", "яковлев"},
+		{"fyodor", "фёдор"},
+		{"yelena", "елена"},
+		{"dmitriy", "дмитрий"},
+		{"maxim", "максим"},
+		{"wladimir", "владимир"},
+		{"kuzmin", "кузмин"},
+		{"иван", "иван"},
+		{"ivanов", "иванов"},
+	}
+	for _, tt := range tests {
+		if got := transliterate(tt.in); got != tt.want {
+			t.Errorf("%s: получено %s, ожидалось %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestHasLatin(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"иван", false},
+		{"ivan", true},
+		{"иvan", true},
+		{"ёж", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := hasLatin(tt.in); got != tt.want {
+			t.Errorf("%q: %v, ожидалось %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"Иван Иванов", []string{"иван", "иванов"}},
+		{"  ИВАНОВ-Петров, Иван!  ", []string{"иванов", "петров", "иван"}},
+		{"O'Neil 42", []string{"o", "neil"}},
+		{"Пётр", []string{"пётр"}},
+		{"", nil},
+		{"123 !!", nil},
+	}
+	for _, tt := range tests {
+		if got := searchTerms(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: получено %q, ожидалось %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNameVariants(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"иван", []string{"иван"}},
+		{"ivan", []string{"ivan", "иван"}},
+		{"пётр", []string{"пётр", "петр"}},
+		{"ваня", []string{"ваня", "иван"}},
+		{"петя", []string{"петя", "пётр", "петр"}},
+		{"саша", []string{"саша", "александр", "александра"}},
+		{"sasha", []string{"sasha", "саша", "александр", "александра"}},
+		{"лёша", []string{"лёша", "леша", "алексей"}},
+		{"леша", []string{"леша", "алексей"}},
+		{"алёша", []string{"алёша", "алеша", "алексей"}},
+		{"алеша", []string{"алеша", "алексей"}},
+		{"лева", []string{"лева", "лев"}},
+		{"fedya", []string{"fedya", "федя", "фёдор", "федор"}},
+		{"слава", []string{"слава", "вячеслав", "владислав", "ярослав", "станислав"}},
+		{"fyodor", []string{"fyodor", "фёдор", "федор"}},
+	}
+	for _, tt := range tests {
+		if got := nameVariants(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: получено %q, ожидалось %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeQuery(t *testing.T) {
+	tests := []struct {
+		in   []string
+		want string
+	}{
+		{[]string{"пётр", "иванов"}, "петр иванов"},
+		{[]string{"fyodor", "ivanov"}, "федор иванов"},
+		{[]string{"ivan", "петров"}, "иван петров"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeQuery(tt.in); got != tt.want {
+			t.Errorf("%q: получено %q, ожидалось %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jackc/pgx/v4"
+	"github.com/zatrasz75/Service/pkg/storage"
+)
+
+// outboxLockKey ключ рекомендательной блокировки, под которой события публикует только одна реплика.
+// Это сохраняет порядок событий одной записи.
+const outboxLockKey = 7_519_220_002
+
+// recordOutbox добавляет событие изменения записи в outbox и доставки подписчикам арендатора
+// в рамках транзакции tx.
+// Для удаления в событие попадает состояние записи до изменения.
+func recordOutbox(ctx context.Context, tx pgx.Tx, personID int, operation string, oldValues, newValues []byte) error {
+	eventType, ok := storage.EventTypes[operation]
+	if !ok {
+		return nil
+	}
+	payload := newValues
+	if payload == nil {
+		payload = oldValues
+	}
+
+	// Доставки подписчикам webhooks создаются вместе с событием и содержат то же тело,
+	// что и сообщение Kafka.
+	a := storage.AuditFrom(ctx)
+	_, err := tx.Exec(ctx, `
+		WITH event AS (
+			INSERT INTO outbox (person_id, tenant_id, event_type, payload, source, actor)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id, person_id, tenant_id, event_type, payload, source, actor, created_at
+		)
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+		SELECT w.id, e.id, e.event_type, jsonb_build_object(
+			'id', e.id, 'type', e.event_type, 'tenant_id', e.tenant_id, 'person_id', e.person_id, 'person', e.payload,
+			'source', e.source, 'actor', e.actor, 'occurred_at', e.created_at)
+		FROM event e
+		JOIN webhooks w ON w.tenant_id = e.tenant_id AND w.active
+			AND (cardinality(w.event_types) = 0 OR e.event_type = ANY(w.event_types));`,
+		personID, storage.TenantFrom(ctx), eventType, payload, a.Source, a.Actor)
+
+	return err
+}
+
+// RelayOutbox передаёт в publish не более limit самых старых событий и удаляет их после успешной публикации.
+// Если publish вернул ошибку, события остаются в outbox и будут отправлены повторно.
+// Пока события публикует другая реплика, возвращает 0.
+func (s *Store) RelayOutbox(ctx context.Context, limit int, publish func([]storage.OutboxEvent) error) (int, error) {
+	var n int
+	err := s.inTx(ctx, func(tx pgx.Tx) error {
+		var locked bool
+		if err := tx.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock($1)", outboxLockKey).Scan(&locked); err != nil || !locked {
+			return err
+		}
+
+		rows, err := tx.Query(ctx, `
+			SELECT id, event_type, tenant_id, person_id, payload, source, actor, created_at
+			FROM outbox ORDER BY id LIMIT $1;`, limit)
+		if err != nil {
+			return err
+		}
+		var events []storage.OutboxEvent
+		var ids []int64
+		for rows.Next() {
+			var e storage.OutboxEvent
+			var payload []byte
+			if err = rows.Scan(&e.ID, &e.Type, &e.TenantID, &e.PersonID, &payload, &e.Source, &e.Actor, &e.OccurredAt); err != nil {
+				rows.Close()
+				return err
+			}
+			e.Person = payload
+			events = append(events, e)
+			ids = append(ids, e.ID)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil || len(events) == 0 {
+			return err
+		}
+
+		if err = publish(events); err != nil {
+			return err
+		}
+		if _, err = tx.Exec(ctx, "DELETE FROM outbox WHERE id = ANY($1);", ids); err != nil {
+			return err
+		}
+		n = len(events)
+		return nil
+	})
+
+	return n, err
+}
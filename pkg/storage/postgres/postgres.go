@@ -2,60 +2,122 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
-	"github.com/zatrasz75/Service/pkg/logger"
+	"github.com/zatrasz75/Service/configs"
 	"github.com/zatrasz75/Service/pkg/storage"
+	"sort"
 	"strconv"
 	"time"
 )
 
+// usersDataColumns столбцы service_data в порядке сканирования scanUsersData.
+const usersDataColumns = "id, name, surname, patronymic, age, gender, nationality, version, created_at, updated_at, deleted_at"
+
+// scanUsersData читает строку, выбранную по usersDataColumns.
+func scanUsersData(row pgx.Row) (storage.UsersData, error) {
+	var data storage.UsersData
+	err := row.Scan(&data.ID, &data.Name, &data.Surname, &data.Patronymic, &data.Age, &data.Gender, &data.Nationality,
+		&data.Version, &data.CreatedAt, &data.UpdatedAt, &data.DeletedAt)
+	return data, err
+}
+
 // Store Хранилище данных
 type Store struct {
-	db *pgxpool.Pool
+	db pool
 }
 
-// New Конструктор
-func New(constr string) (*Store, error) {
+// New Конструктор. Создаёт единственный пул соединений с настройками из конфигурации.
+func New(cfg configs.DataBase) (*Store, error) {
+	poolCfg, err := pgxpool.ParseConfig(cfg.ConnStr)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MaxConns > 0 {
+		poolCfg.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		poolCfg.MinConns = cfg.MinConns
+	}
+	if cfg.MaxConnLifetime > 0 {
+		poolCfg.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolCfg.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		poolCfg.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+	// Политики строк не пропускают соединения без app.tenant_id, поэтому значение
+	// устанавливается всегда.
+	poolCfg.BeforeAcquire = setAllTenants
+	if cfg.RowLevelSecurity {
+		poolCfg.BeforeAcquire = setTenant
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	var db, err = pgxpool.Connect(ctx, constr)
+	db, err := pgxpool.ConnectConfig(ctx, poolCfg)
 	if err != nil {
 		return nil, err
 	}
 	s := Store{
-		db: db,
+		db: pool{db},
 	}
 	return &s, nil
 }
 
-func (s *Store) CreateDataTable() error {
-	qwery := `CREATE TABLE IF NOT EXISTS "service_data" (
-    id SERIAL PRIMARY KEY,
-    name VARCHAR(255),
-    surname VARCHAR(255),
-    patronymic VARCHAR(255),
-    age INT,
-    gender VARCHAR(255),
-    nationality VARCHAR(255)
-);`
+// setTenant устанавливает арендатора из контекста в app.tenant_id соединения перед его выдачей из пула.
+// Значение storage.AllTenants устанавливается только фоновыми задачами. Соединение, на котором
+// установить значение не удалось, закрывается.
+func setTenant(ctx context.Context, conn *pgx.Conn) bool {
+	_, err := conn.Exec(ctx, "SELECT set_config('app.tenant_id', $1, false)", storage.TenantFrom(ctx))
+	return err == nil
+}
 
-	_, err := s.db.Exec(context.Background(), qwery)
-	if err != nil {
-		logger.Error("не удалось создать таблицу service_data %s", err)
-		return err
-	}
+// setAllTenants отключает политики строк для соединения, когда арендатор ограничивается
+// только условиями запросов.
+func setAllTenants(ctx context.Context, conn *pgx.Conn) bool {
+	return setTenant(storage.WithTenant(ctx, storage.AllTenants), conn)
+}
 
-	return nil
+// Close закрывает пул соединений.
+func (s *Store) Close() {
+	s.db.Close()
+}
+
+// Ping проверяет соединение с базой данных.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.Ping(ctx)
+}
+
+// Stat возвращает состояние пула соединений.
+func (s *Store) Stat() *pgxpool.Stat {
+	return s.db.Stat()
 }
 
 // SaveDataToDatabase сохраняет данные в базу данных и возвращает ее id.
-func (s *Store) SaveDataToDatabase(d storage.Data) (int, error) {
+func (s *Store) SaveDataToDatabase(ctx context.Context, d storage.Data) (int, error) {
+	var id int
+	err := s.inTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		id, err = insertData(ctx, tx, d)
+		return err
+	})
+
+	return id, err
+}
+
+// insertData добавляет запись и её историю в рамках транзакции tx.
+func insertData(ctx context.Context, tx pgx.Tx, d storage.Data) (int, error) {
 	var id int
-	err := s.db.QueryRow(context.Background(), `
-		INSERT INTO service_data (name, surname, patronymic, age, gender, nationality)
-		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id;
+	var newValues []byte
+	err := tx.QueryRow(ctx, `
+		INSERT INTO service_data (name, surname, patronymic, age, gender, nationality, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, `+rowJSON+`;
 		`,
 		d.Name,
 		d.Surname,
@@ -63,22 +125,41 @@ func (s *Store) SaveDataToDatabase(d storage.Data) (int, error) {
 		d.Age,
 		d.Gender,
 		d.Nationality,
-	).Scan(&id)
+		storage.TenantFrom(ctx),
+	).Scan(&id, &newValues)
+	if err != nil {
+		return 0, err
+	}
 
-	return id, err
+	return id, recordHistory(ctx, tx, id, storage.OpInsert, nil, newValues)
+}
+
+// filterClause формирует условие WHERE по арендатору из контекста и параметрам фильтрации
+// и дополняет args его аргументами.
+func filterClause(ctx context.Context, filter storage.Filter, args []interface{}) (string, []interface{}) {
+	args = append(args, storage.TenantFrom(ctx))
+	where := "tenant_id = $" + strconv.Itoa(len(args))
+	if filter.Gender != "" {
+		args = append(args, filter.Gender)
+		where += " AND gender = $" + strconv.Itoa(len(args))
+	}
+	if !filter.IncludeDeleted {
+		where += " AND deleted_at IS NULL"
+	}
+
+	return where, args
 }
 
 // Select выполняет SQL-запрос для выборки данных из таблицы service_data с фильтрами и пагинацией.
-func (s *Store) Select(genderFilter string, page, pageSize int) ([]storage.UsersData, error) {
+// Записи, помеченные как удалённые, возвращаются только при filter.IncludeDeleted.
+func (s *Store) Select(ctx context.Context, filter storage.Filter, page, pageSize int) ([]storage.UsersData, error) {
 	// Создаем SQL-запрос с учетом фильтра и пагинации.
-	query := "SELECT * FROM service_data WHERE true"
-	if genderFilter != "" {
-		query += fmt.Sprintf(" AND gender = '%s'", genderFilter)
-	}
-	query += fmt.Sprintf(" LIMIT %d OFFSET %d", pageSize, (page-1)*pageSize)
+	where, args := filterClause(ctx, filter, nil)
+	query := "SELECT " + usersDataColumns + " FROM service_data WHERE " + where
+	query += fmt.Sprintf(" ORDER BY id LIMIT %d OFFSET %d", pageSize, (page-1)*pageSize)
 
 	// Выполняем запрос к базе данных.
-	rows, err := s.db.Query(context.Background(), query)
+	rows, err := s.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -86,63 +167,189 @@ func (s *Store) Select(genderFilter string, page, pageSize int) ([]storage.Users
 
 	var result []storage.UsersData
 	for rows.Next() {
-		var data storage.UsersData
-		if err = rows.Scan(&data.ID, &data.Name, &data.Surname, &data.Patronymic, &data.Age, &data.Gender, &data.Nationality); err != nil {
+		data, err := scanUsersData(rows)
+		if err != nil {
 			return nil, err
 		}
 		result = append(result, data)
 	}
 
-	return result, nil
+	return result, rows.Err()
+}
+
+// GetDataByID возвращает неудалённую запись арендатора по идентификатору.
+func (s *Store) GetDataByID(ctx context.Context, id int) (storage.UsersData, error) {
+	data, err := scanUsersData(s.db.QueryRow(ctx,
+		"SELECT "+usersDataColumns+" FROM service_data WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL",
+		id, storage.TenantFrom(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.UsersData{}, storage.ErrNotFound
+	}
+
+	return data, err
 }
 
-// DeleteDataByID Создаем SQL-запрос для удаления данных по идентификатору.
-func (s *Store) DeleteDataByID(id int) error {
-	delet := "DELETE FROM service_data WHERE id = $1"
+// DeleteDataByID помечает запись как удалённую (мягкое удаление).
+func (s *Store) DeleteDataByID(ctx context.Context, id int) error {
+	return s.inTx(ctx, func(tx pgx.Tx) error {
+		return softDelete(ctx, tx, id)
+	})
+}
+
+// softDelete помечает запись как удалённую и фиксирует историю в рамках транзакции tx.
+func softDelete(ctx context.Context, tx pgx.Tx, id int) error {
+	oldValues, err := snapshot(ctx, tx, id, false)
+	if err != nil {
+		return err
+	}
 
-	_, err := s.db.Exec(context.Background(), delet, id)
+	var newValues []byte
+	err = tx.QueryRow(ctx,
+		"UPDATE service_data SET deleted_at = now() WHERE id = $1 RETURNING "+rowJSON, id,
+	).Scan(&newValues)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return recordHistory(ctx, tx, id, storage.OpDelete, oldValues, newValues)
+}
+
+// RestoreDataByID восстанавливает запись, помеченную как удалённая.
+func (s *Store) RestoreDataByID(ctx context.Context, id int) error {
+	return s.inTx(ctx, func(tx pgx.Tx) error {
+		oldValues, err := snapshot(ctx, tx, id, true)
+		if err != nil {
+			return err
+		}
+
+		var newValues []byte
+		err = tx.QueryRow(ctx,
+			"UPDATE service_data SET deleted_at = NULL WHERE id = $1 RETURNING "+rowJSON, id,
+		).Scan(&newValues)
+		if err != nil {
+			return err
+		}
+
+		return recordHistory(ctx, tx, id, storage.OpRestore, oldValues, newValues)
+	})
 }
 
-// UpdateDataByID обновляет данные сущности по ее идентификатору.
-func (s *Store) UpdateDataByID(id int, newData storage.UsersData) error {
-	_, err := s.db.Exec(context.Background(), `
+// PurgeDeleted окончательно удаляет записи всех арендаторов, помеченные как удалённые
+// раньше чем olderThan назад.
+func (s *Store) PurgeDeleted(ctx context.Context, olderThan time.Duration) (int64, error) {
+	a := storage.AuditFrom(ctx)
+	tag, err := s.db.Exec(ctx, `
+		WITH purged AS (
+			DELETE FROM service_data
+			WHERE deleted_at IS NOT NULL AND deleted_at < now() - $1::interval
+			RETURNING id, tenant_id, `+rowJSON+` AS old_values
+		)
+		INSERT INTO service_data_history (person_id, tenant_id, operation, old_values, source, actor)
+		SELECT id, tenant_id, $2, old_values, $3, $4 FROM purged;`,
+		olderThan.String(), storage.OpPurge, a.Source, a.Actor)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// UpdateDataByID обновляет данные сущности по ее идентификатору и возвращает новую версию записи.
+func (s *Store) UpdateDataByID(ctx context.Context, id int, newData storage.UsersData, version int) (int, error) {
+	var newVersion int
+	err := s.inTx(ctx, func(tx pgx.Tx) error {
+		oldValues, err := snapshot(ctx, tx, id, false)
+		if err != nil {
+			return err
+		}
+
+		var newValues []byte
+		err = tx.QueryRow(ctx, `
         UPDATE service_data 
         SET name = $2, surname = $3, patronymic = $4, age = $5, gender = $6, nationality = $7
-        WHERE id = $1;
+        WHERE id = $1 AND ($8 = 0 OR version = $8)
+        RETURNING version, `+rowJSON+`;
     `,
-		id,
-		newData.Name,
-		newData.Surname,
-		newData.Patronymic,
-		newData.Age,
-		newData.Gender,
-		newData.Nationality,
-	)
-	return err
-}
-
-// PartialUpdateDataByID частично обновляет данные сущности по ее идентификатору.
-func (s *Store) PartialUpdateDataByID(id int, partialData map[string]interface{}) error {
+			id,
+			newData.Name,
+			newData.Surname,
+			newData.Patronymic,
+			newData.Age,
+			newData.Gender,
+			newData.Nationality,
+			version,
+		).Scan(&newVersion, &newValues)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.ErrVersionConflict
+		}
+		if err != nil {
+			return err
+		}
+
+		return recordHistory(ctx, tx, id, storage.OpUpdate, oldValues, newValues)
+	})
+
+	return newVersion, err
+}
+
+// PartialUpdateDataByID частично обновляет данные сущности по ее идентификатору и возвращает новую версию записи.
+func (s *Store) PartialUpdateDataByID(ctx context.Context, id int, partialData map[string]interface{}, version int) (int, error) {
+	var newVersion int
+	err := s.inTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		newVersion, err = partialUpdate(ctx, tx, id, partialData, version)
+		return err
+	})
+
+	return newVersion, err
+}
+
+// partialUpdate частично обновляет запись и фиксирует историю в рамках транзакции tx.
+func partialUpdate(ctx context.Context, tx pgx.Tx, id int, partialData map[string]interface{}, version int) (int, error) {
+	if len(partialData) == 0 {
+		return 0, storage.ErrInvalidPatch
+	}
+
+	// Сортируем поля, чтобы текст запроса не зависел от порядка обхода map.
+	keys := make([]string, 0, len(partialData))
+	for key := range partialData {
+		if _, ok := storage.PatchableFields[key]; !ok {
+			return 0, fmt.Errorf("%w: поле %s", storage.ErrInvalidPatch, key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
 	// Динамический SQL-запрос на основе частичных данных.
 	query := "UPDATE service_data SET"
-	args := []interface{}{id}
-	argIndex := 2 // Индекс первого аргумента после id.
+	args := []interface{}{id, version}
+	argIndex := 3 // Индекс первого аргумента после id и версии.
 
-	for key, value := range partialData {
+	for _, key := range keys {
 		query += " " + key + " = $" + strconv.Itoa(argIndex) + ","
-		args = append(args, value)
+		args = append(args, partialData[key])
 		argIndex++
 	}
 
 	// Удаление последней запятой из запроса.
 	query = query[:len(query)-1]
 
-	query += " WHERE id = $1;"
-	_, err := s.db.Exec(context.Background(), query, args...)
-	return err
+	query += " WHERE id = $1 AND ($2 = 0 OR version = $2) RETURNING version, " + rowJSON + ";"
+
+	oldValues, err := snapshot(ctx, tx, id, false)
+	if err != nil {
+		return 0, err
+	}
+
+	var newVersion int
+	var newValues []byte
+	err = tx.QueryRow(ctx, query, args...).Scan(&newVersion, &newValues)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, storage.ErrVersionConflict
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return newVersion, recordHistory(ctx, tx, id, storage.OpUpdate, oldValues, newValues)
 }
@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"strconv"
+	"strings"
+)
+
+// Search выполняет полнотекстовый и нечёткий поиск по имени, фамилии и отчеству.
+// Результаты упорядочены по убыванию релевантности.
+func (s *Store) Search(ctx context.Context, query string, filter storage.Filter, limit int) ([]storage.SearchResult, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return []storage.SearchResult{}, nil
+	}
+
+	// Каждое слово запроса и его варианты объединяются через ИЛИ, допускается совпадение по префиксу.
+	// Слова содержат только буквы, поэтому их можно передать в to_tsquery без экранирования.
+	var alternatives []string
+	for _, t := range terms {
+		for _, v := range nameVariants(t) {
+			alternatives = append(alternatives, v+":*")
+		}
+	}
+	tsquery := strings.Join(alternatives, " | ")
+	fuzzy := normalizeQuery(terms)
+
+	args := []interface{}{tsquery, fuzzy}
+	where, args := filterClause(ctx, filter, args)
+	args = append(args, limit)
+
+	sql := `
+		SELECT ` + usersDataColumns + `,
+			GREATEST(
+				ts_rank(search_vector, to_tsquery('simple', $1)),
+				word_similarity($2, full_name)
+			) AS score
+		FROM service_data
+		WHERE ` + where + `
+			AND (search_vector @@ to_tsquery('simple', $1) OR $2 <% full_name)
+		ORDER BY score DESC, id
+		LIMIT $` + strconv.Itoa(len(args))
+
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []storage.SearchResult{}
+	for rows.Next() {
+		var r storage.SearchResult
+		d := &r.UsersData
+		if err = rows.Scan(&d.ID, &d.Name, &d.Surname, &d.Patronymic, &d.Age, &d.Gender, &d.Nationality,
+			&d.Version, &d.CreatedAt, &d.UpdatedAt, &d.DeletedAt, &r.Score); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jackc/pgx/v4"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"strconv"
+)
+
+// Stats возвращает статистику по записям, подходящим под фильтр.
+// Возраст группируется по интервалам ширины bucketWidth. Все запросы выполняются
+// в одной транзакции, поэтому статистика согласована.
+func (s *Store) Stats(ctx context.Context, filter storage.Filter, bucketWidth int) (storage.Stats, error) {
+	stats := storage.Stats{
+		ByGender:      []storage.GroupCount{},
+		ByNationality: []storage.GroupCount{},
+		ByAge:         []storage.AgeBucket{},
+	}
+	where, args := filterClause(ctx, filter, nil)
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return stats, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err = tx.QueryRow(ctx, "SELECT count(*) FROM service_data WHERE "+where, args...).Scan(&stats.Total); err != nil {
+		return stats, err
+	}
+
+	if stats.ByGender, err = groupCounts(ctx, tx, "gender", where, args); err != nil {
+		return stats, err
+	}
+	if stats.ByNationality, err = groupCounts(ctx, tx, "nationality", where, args); err != nil {
+		return stats, err
+	}
+
+	// Возраст 0 означает, что сервис обогащения не определил возраст.
+	ageWhere := where + " AND age > 0"
+	err = tx.QueryRow(ctx, `
+		SELECT count(age), coalesce(min(age), 0), coalesce(max(age), 0), coalesce(avg(age), 0)::float8,
+			coalesce(percentile_cont(0.5) WITHIN GROUP (ORDER BY age), 0)::float8,
+			coalesce(percentile_cont(0.9) WITHIN GROUP (ORDER BY age), 0)::float8,
+			coalesce(percentile_cont(0.99) WITHIN GROUP (ORDER BY age), 0)::float8
+		FROM service_data WHERE `+ageWhere, args...,
+	).Scan(&stats.Age.Count, &stats.Age.Min, &stats.Age.Max, &stats.Age.Avg, &stats.Age.P50, &stats.Age.P90, &stats.Age.P99)
+	if err != nil {
+		return stats, err
+	}
+
+	width := strconv.Itoa(bucketWidth)
+	rows, err := tx.Query(ctx, `
+		SELECT (age / `+width+`) * `+width+` AS bucket, count(*)
+		FROM service_data WHERE `+ageWhere+`
+		GROUP BY bucket ORDER BY bucket`, args...)
+	if err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b storage.AgeBucket
+		if err = rows.Scan(&b.From, &b.Count); err != nil {
+			return stats, err
+		}
+		b.To = b.From + bucketWidth - 1
+		stats.ByAge = append(stats.ByAge, b)
+	}
+	if err = rows.Err(); err != nil {
+		return stats, err
+	}
+
+	return stats, tx.Commit(ctx)
+}
+
+// groupCounts возвращает количество записей по значениям столбца column.
+func groupCounts(ctx context.Context, tx pgx.Tx, column, where string, args []interface{}) ([]storage.GroupCount, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT coalesce(`+column+`, ''), count(*)
+		FROM service_data WHERE `+where+`
+		GROUP BY 1 ORDER BY 2 DESC, 1`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []storage.GroupCount{}
+	for rows.Next() {
+		var g storage.GroupCount
+		if err = rows.Scan(&g.Value, &g.Count); err != nil {
+			return nil, err
+		}
+		result = append(result, g)
+	}
+
+	return result, rows.Err()
+}
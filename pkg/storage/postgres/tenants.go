@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v4"
+	"github.com/zatrasz75/Service/pkg/storage"
+)
+
+// EnrichmentSettings возвращает настройки обогащения арендатора из контекста.
+// Если настройки не заданы, возвращает storage.DefaultEnrichment.
+func (s *Store) EnrichmentSettings(ctx context.Context) (storage.EnrichmentSettings, error) {
+	var e storage.EnrichmentSettings
+	err := s.db.QueryRow(ctx, `
+		SELECT enrich_age, enrich_gender, enrich_nationality, country_id, enrichment_api_key, updated_at
+		FROM tenants WHERE id = $1;`, storage.TenantFrom(ctx),
+	).Scan(&e.Age, &e.Gender, &e.Nationality, &e.CountryID, &e.APIKey, &e.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.DefaultEnrichment, nil
+	}
+
+	return e, err
+}
+
+// SetEnrichmentSettings сохраняет настройки обогащения арендатора из контекста.
+// Пустой ключ API сохраняет текущий.
+func (s *Store) SetEnrichmentSettings(ctx context.Context, e storage.EnrichmentSettings) (storage.EnrichmentSettings, error) {
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO tenants (id, enrich_age, enrich_gender, enrich_nationality, country_id, enrichment_api_key)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE SET
+			enrich_age = EXCLUDED.enrich_age,
+			enrich_gender = EXCLUDED.enrich_gender,
+			enrich_nationality = EXCLUDED.enrich_nationality,
+			country_id = EXCLUDED.country_id,
+			enrichment_api_key = COALESCE(NULLIF(EXCLUDED.enrichment_api_key, ''), tenants.enrichment_api_key),
+			updated_at = now()
+		RETURNING enrich_age, enrich_gender, enrich_nationality, country_id, enrichment_api_key, updated_at;`,
+		storage.TenantFrom(ctx), e.Age, e.Gender, e.Nationality, e.CountryID, e.APIKey,
+	).Scan(&e.Age, &e.Gender, &e.Nationality, &e.CountryID, &e.APIKey, &e.UpdatedAt)
+
+	return e, err
+}
@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/zatrasz75/Service/pkg/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+)
+
+// pool Пул соединений, создающий span OpenTelemetry для каждого запроса,
+// в том числе выполненного в транзакции.
+type pool struct {
+	*pgxpool.Pool
+}
+
+func (p pool) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return tracedExec(ctx, p.Pool, sql, args...)
+}
+
+func (p pool) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return tracedQuery(ctx, p.Pool, sql, args...)
+}
+
+func (p pool) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return tracedQueryRow(ctx, p.Pool, sql, args...)
+}
+
+func (p pool) Begin(ctx context.Context) (pgx.Tx, error) {
+	tx, err := p.Pool.Begin(ctx)
+	return tracedTx{Tx: tx}, err
+}
+
+func (p pool) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	tx, err := p.Pool.BeginTx(ctx, opts)
+	return tracedTx{Tx: tx}, err
+}
+
+// tracedTx Транзакция, создающая span для каждого запроса.
+type tracedTx struct {
+	pgx.Tx
+}
+
+func (tx tracedTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return tracedExec(ctx, tx.Tx, sql, args...)
+}
+
+func (tx tracedTx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return tracedQuery(ctx, tx.Tx, sql, args...)
+}
+
+func (tx tracedTx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return tracedQueryRow(ctx, tx.Tx, sql, args...)
+}
+
+func (tx tracedTx) Commit(ctx context.Context) error {
+	_, span := startQuery(ctx, "COMMIT")
+	err := tx.Tx.Commit(ctx)
+	tracing.End(span, err)
+	return err
+}
+
+// querier Общие методы пула и транзакции.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+func tracedExec(ctx context.Context, q querier, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	ctx, span := startQuery(ctx, sql)
+	tag, err := q.Exec(ctx, sql, args...)
+	tracing.End(span, err)
+	return tag, err
+}
+
+func tracedQuery(ctx context.Context, q querier, sql string, args ...interface{}) (pgx.Rows, error) {
+	ctx, span := startQuery(ctx, sql)
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		tracing.End(span, err)
+		return rows, err
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func tracedQueryRow(ctx context.Context, q querier, sql string, args ...interface{}) pgx.Row {
+	ctx, span := startQuery(ctx, sql)
+	return tracedRow{row: q.QueryRow(ctx, sql, args...), span: span}
+}
+
+// startQuery начинает span запроса с именем по первому слову SQL (SELECT, INSERT, ...).
+func startQuery(ctx context.Context, sql string) (context.Context, trace.Span) {
+	op := "SQL"
+	if fields := strings.Fields(sql); len(fields) > 0 {
+		op = strings.ToUpper(fields[0])
+	}
+	return tracing.Start(ctx, "postgres "+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBOperation(op), semconv.DBStatement(strings.TrimSpace(sql))))
+}
+
+// tracedRows Результат запроса, завершающий span при закрытии.
+type tracedRows struct {
+	pgx.Rows
+	span trace.Span
+	done bool
+}
+
+func (r *tracedRows) Close() {
+	r.Rows.Close()
+	if !r.done {
+		r.done = true
+		tracing.End(r.span, r.Rows.Err())
+	}
+}
+
+func (r *tracedRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	// Строки закончились: pgx уже закрыл результат, поэтому span завершается здесь.
+	r.Close()
+	return false
+}
+
+// tracedRow Строка результата, завершающая span при чтении.
+type tracedRow struct {
+	row  pgx.Row
+	span trace.Span
+}
+
+func (r tracedRow) Scan(dest ...interface{}) error {
+	err := r.row.Scan(dest...)
+	if err == pgx.ErrNoRows {
+		tracing.End(r.span, nil)
+	} else {
+		tracing.End(r.span, err)
+	}
+	return err
+}
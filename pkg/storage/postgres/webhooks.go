@@ -0,0 +1,232 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v4"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"time"
+)
+
+// webhookColumns столбцы webhooks в порядке сканирования scanWebhook.
+const webhookColumns = "id, url, event_types, secret, active, created_at, updated_at"
+
+// deliveryColumns столбцы webhook_deliveries в порядке сканирования scanDelivery.
+const deliveryColumns = `id, webhook_id, event_id, event_type, payload, status, attempts,
+	next_attempt_at, last_status_code, last_error, created_at, updated_at`
+
+func scanWebhook(row pgx.Row) (storage.Webhook, error) {
+	var w storage.Webhook
+	err := row.Scan(&w.ID, &w.URL, &w.EventTypes, &w.Secret, &w.Active, &w.CreatedAt, &w.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return w, storage.ErrNotFound
+	}
+	if w.EventTypes == nil {
+		w.EventTypes = []string{}
+	}
+	return w, err
+}
+
+func scanDelivery(row pgx.Row, extra ...interface{}) (storage.WebhookDelivery, error) {
+	var d storage.WebhookDelivery
+	var payload []byte
+	dest := append([]interface{}{&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.UpdatedAt}, extra...)
+	err := row.Scan(dest...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return d, storage.ErrNotFound
+	}
+	d.Payload = payload
+	return d, err
+}
+
+// CreateWebhook добавляет подписку арендатора и возвращает её вместе с секретом.
+func (s *Store) CreateWebhook(ctx context.Context, w storage.Webhook) (storage.Webhook, error) {
+	return scanWebhook(s.db.QueryRow(ctx, `
+		INSERT INTO webhooks (url, event_types, secret, active, tenant_id) VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+webhookColumns+`;`,
+		w.URL, w.EventTypes, w.Secret, w.Active, storage.TenantFrom(ctx)))
+}
+
+// ListWebhooks возвращает все подписки арендатора.
+func (s *Store) ListWebhooks(ctx context.Context) ([]storage.Webhook, error) {
+	rows, err := s.db.Query(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE tenant_id = $1 ORDER BY id;",
+		storage.TenantFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []storage.Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, w)
+	}
+
+	return result, rows.Err()
+}
+
+// GetWebhook возвращает подписку арендатора по идентификатору.
+func (s *Store) GetWebhook(ctx context.Context, id int64) (storage.Webhook, error) {
+	return scanWebhook(s.db.QueryRow(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE id = $1 AND tenant_id = $2;",
+		id, storage.TenantFrom(ctx)))
+}
+
+// UpdateWebhook заменяет адрес, типы событий и состояние подписки.
+// Пустой секрет сохраняет текущий.
+func (s *Store) UpdateWebhook(ctx context.Context, w storage.Webhook) (storage.Webhook, error) {
+	return scanWebhook(s.db.QueryRow(ctx, `
+		UPDATE webhooks
+		SET url = $2, event_types = $3, secret = COALESCE(NULLIF($4, ''), secret), active = $5, updated_at = now()
+		WHERE id = $1 AND tenant_id = $6
+		RETURNING `+webhookColumns+`;`,
+		w.ID, w.URL, w.EventTypes, w.Secret, w.Active, storage.TenantFrom(ctx)))
+}
+
+// DeleteWebhook удаляет подписку вместе с её доставками.
+func (s *Store) DeleteWebhook(ctx context.Context, id int64) error {
+	tag, err := s.db.Exec(ctx, "DELETE FROM webhooks WHERE id = $1 AND tenant_id = $2;", id, storage.TenantFrom(ctx))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// WebhookDeliveries возвращает последние доставки подписки, при непустом status — только в этом состоянии.
+func (s *Store) WebhookDeliveries(ctx context.Context, webhookID int64, status string, limit int) ([]storage.WebhookDelivery, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE webhook_id = (SELECT id FROM webhooks WHERE id = $1 AND tenant_id = $4)
+			AND ($2 = '' OR status = $2)
+		ORDER BY id DESC LIMIT $3;`, webhookID, status, limit, storage.TenantFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []storage.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, d)
+	}
+
+	return result, rows.Err()
+}
+
+// GetWebhookDelivery возвращает доставку вместе с журналом попыток.
+func (s *Store) GetWebhookDelivery(ctx context.Context, webhookID, id int64) (storage.WebhookDelivery, error) {
+	d, err := scanDelivery(s.db.QueryRow(ctx, `
+		SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE webhook_id = (SELECT id FROM webhooks WHERE id = $1 AND tenant_id = $3) AND id = $2;`,
+		webhookID, id, storage.TenantFrom(ctx)))
+	if err != nil {
+		return d, err
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT attempted_at, status_code, error, duration_ms
+		FROM webhook_attempts WHERE delivery_id = $1 ORDER BY id;`, id)
+	if err != nil {
+		return d, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a storage.WebhookAttempt
+		if err = rows.Scan(&a.AttemptedAt, &a.StatusCode, &a.Error, &a.DurationMS); err != nil {
+			return d, err
+		}
+		d.AttemptLog = append(d.AttemptLog, a)
+	}
+
+	return d, rows.Err()
+}
+
+// ClaimWebhookDeliveries выбирает не более limit доставок активных подписок всех арендаторов, время отправки
+// которых наступило, и откладывает их следующую попытку на lease. Доставки выключенных подписок остаются
+// в очереди до их включения. Если обработчик не запишет результат
+// за это время, доставка будет выбрана повторно.
+func (s *Store) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]storage.WebhookTask, error) {
+	rows, err := s.db.Query(ctx, `
+		WITH due AS (
+			SELECT d.id FROM webhook_deliveries d
+			WHERE d.status = $1 AND d.next_attempt_at <= now()
+				AND EXISTS (SELECT 1 FROM webhooks w WHERE w.id = d.webhook_id AND w.active)
+			ORDER BY d.next_attempt_at, d.id
+			LIMIT $2
+			FOR UPDATE OF d SKIP LOCKED
+		), claimed AS (
+			UPDATE webhook_deliveries d SET next_attempt_at = now() + $3::interval
+			FROM due WHERE d.id = due.id
+			RETURNING d.*
+		)
+		SELECT c.id, c.webhook_id, c.event_id, c.event_type, c.payload, c.status, c.attempts,
+			c.next_attempt_at, c.last_status_code, c.last_error, c.created_at, c.updated_at, w.url, w.secret
+		FROM claimed c JOIN webhooks w ON w.id = c.webhook_id
+		ORDER BY c.id;`,
+		storage.DeliveryPending, limit, lease.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []storage.WebhookTask
+	for rows.Next() {
+		var t storage.WebhookTask
+		t.WebhookDelivery, err = scanDelivery(rows, &t.URL, &t.Secret)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, t)
+	}
+
+	return result, rows.Err()
+}
+
+// FinishWebhookAttempt записывает попытку доставки и переводит доставку в состояние status.
+// Для состояния pending следующая попытка выполняется в nextAttempt.
+func (s *Store) FinishWebhookAttempt(ctx context.Context, deliveryID int64, attempt storage.WebhookAttempt, status string, nextAttempt time.Time) error {
+	return s.inTx(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO webhook_attempts (delivery_id, attempted_at, status_code, error, duration_ms)
+			VALUES ($1, $2, $3, $4, $5);`,
+			deliveryID, attempt.AttemptedAt, attempt.StatusCode, attempt.Error, attempt.DurationMS)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE webhook_deliveries
+			SET attempts = attempts + 1, status = $2, next_attempt_at = $3,
+				last_status_code = $4, last_error = $5, updated_at = now()
+			WHERE id = $1;`,
+			deliveryID, status, nextAttempt, attempt.StatusCode, attempt.Error)
+		return err
+	})
+}
+
+// ReplayWebhookDeliveries возвращает неудавшиеся доставки подписки в очередь с обнулённым
+// счётчиком попыток. При deliveryID > 0 повторяется только указанная доставка.
+// Возвращает количество доставок, поставленных в очередь.
+func (s *Store) ReplayWebhookDeliveries(ctx context.Context, webhookID, deliveryID int64) (int64, error) {
+	tag, err := s.db.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $1, attempts = 0, next_attempt_at = now(), updated_at = now()
+		WHERE webhook_id = (SELECT id FROM webhooks WHERE id = $2 AND tenant_id = $5)
+			AND status = $3 AND ($4 = 0 OR id = $4);`,
+		storage.DeliveryPending, webhookID, storage.DeliveryFailed, deliveryID, storage.TenantFrom(ctx))
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
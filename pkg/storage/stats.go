@@ -0,0 +1,34 @@
+package storage
+
+// Stats Агрегированная статистика по записям.
+type Stats struct {
+	Total         int          `json:"total"`
+	ByGender      []GroupCount `json:"by_gender"`
+	ByNationality []GroupCount `json:"by_nationality"`
+	ByAge         []AgeBucket  `json:"by_age"`
+	Age           AgeStats     `json:"age"`
+}
+
+// GroupCount Количество записей с одинаковым значением поля.
+type GroupCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// AgeBucket Количество записей в возрастном интервале [From, To].
+type AgeBucket struct {
+	From  int `json:"from"`
+	To    int `json:"to"`
+	Count int `json:"count"`
+}
+
+// AgeStats Статистика возраста. Записи без известного возраста не учитываются.
+type AgeStats struct {
+	Count int     `json:"count"`
+	Min   int     `json:"min"`
+	Max   int     `json:"max"`
+	Avg   float64 `json:"avg"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+}
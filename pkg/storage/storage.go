@@ -1,5 +1,23 @@
 package storage
 
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrNotFound возвращается, если запись с указанным идентификатором не найдена.
+var ErrNotFound = errors.New("запись не найдена")
+
+// ErrInvalidPatch возвращается, если данные частичного обновления не соответствуют схеме UsersData.
+var ErrInvalidPatch = errors.New("некорректные данные для частичного обновления")
+
+// ErrVersionConflict возвращается, если запись была изменена после чтения клиентом.
+var ErrVersionConflict = errors.New("версия записи не совпадает")
+
 type Data struct {
 	Name       string `json:"name"`
 	Surname    string `json:"surname"`
@@ -20,13 +38,156 @@ type UsersData struct {
 	Age         int    `json:"age"`
 	Gender      string `json:"gender"`
 	Nationality string `json:"nationality"`
+	Version     int    `json:"version"` // увеличивается при каждом изменении записи
+
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
+// Типы полей, допустимых в частичном обновлении.
+const (
+	FieldString  = "string"
+	FieldInteger = "integer"
+)
+
+// PatchableFields Поля UsersData, которые можно изменять частичным обновлением, и их типы.
+var PatchableFields = map[string]string{
+	"name":        FieldString,
+	"surname":     FieldString,
+	"patronymic":  FieldString,
+	"age":         FieldInteger,
+	"gender":      FieldString,
+	"nationality": FieldString,
+}
+
+// NormalizeField проверяет значение поля частичного обновления по схеме PatchableFields.
+// value — значение, полученное при разборе JSON: nil, string, json.Number или float64.
+// Значение nil очищает поле и заменяется нулевым значением его типа.
+func NormalizeField(field string, value interface{}) (interface{}, error) {
+	kind, ok := PatchableFields[field]
+	if !ok {
+		return nil, fmt.Errorf("%w: поле %s нельзя изменять", ErrInvalidPatch, field)
+	}
+
+	switch kind {
+	case FieldString:
+		if value == nil {
+			return "", nil
+		}
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: поле %s должно быть строкой", ErrInvalidPatch, field)
+		}
+		return str, nil
+	case FieldInteger:
+		if value == nil {
+			return 0, nil
+		}
+		var n int64
+		switch num := value.(type) {
+		case json.Number:
+			v, err := num.Int64()
+			if err != nil {
+				return nil, fmt.Errorf("%w: поле %s должно быть целым числом", ErrInvalidPatch, field)
+			}
+			n = v
+		case float64:
+			if num != math.Trunc(num) || num > math.MaxInt32 {
+				return nil, fmt.Errorf("%w: поле %s должно быть целым числом", ErrInvalidPatch, field)
+			}
+			n = int64(num)
+		default:
+			return nil, fmt.Errorf("%w: поле %s должно быть числом", ErrInvalidPatch, field)
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("%w: поле %s должно быть неотрицательным целым", ErrInvalidPatch, field)
+		}
+		return int(n), nil
+	}
+
+	return nil, fmt.Errorf("%w: неизвестный тип поля %s", ErrInvalidPatch, field)
+}
+
+// SearchResult Запись, найденная поиском, с оценкой релевантности.
+type SearchResult struct {
+	UsersData
+	Score float64 `json:"score"`
+}
+
+// Filter Параметры фильтрации выборки.
+type Filter struct {
+	Gender         string
+	IncludeDeleted bool // включать записи, помеченные как удалённые
+}
+
+// Database Хранилище данных о людях. Контекст методов записи несёт информацию Audit.
+// Запросы ограничены арендатором из контекста (WithTenant), кроме методов фоновых задач,
+// обрабатывающих данные всех арендаторов: PurgeDeleted, RelayOutbox, ClaimWebhookDeliveries.
+// Параметр version методов обновления задаёт ожидаемую версию записи, 0 отключает проверку.
 type Database interface {
-	CreateDataTable() error
-	SaveDataToDatabase(d Data) (int, error)
-	Select(genderFilter string, page, pageSize int) ([]UsersData, error)
-	DeleteDataByID(id int) error
-	UpdateDataByID(id int, newData UsersData) error
-	PartialUpdateDataByID(id int, partialData map[string]interface{}) error
+	SaveDataToDatabase(ctx context.Context, d Data) (int, error)
+	Select(ctx context.Context, filter Filter, page, pageSize int) ([]UsersData, error)
+	GetDataByID(ctx context.Context, id int) (UsersData, error)
+	// Search ищет записи по имени, фамилии и отчеству с учётом опечаток и вариантов написания.
+	Search(ctx context.Context, query string, filter Filter, limit int) ([]SearchResult, error)
+	// Stats возвращает агрегированную статистику с группировкой возраста по интервалам bucketWidth.
+	Stats(ctx context.Context, filter Filter, bucketWidth int) (Stats, error)
+	// Export передаёт в fn все записи, подходящие под фильтр, не загружая выборку в память.
+	Export(ctx context.Context, filter Filter, fn func(UsersData) error) error
+	// Count возвращает количество записей, подходящих под фильтр.
+	Count(ctx context.Context, filter Filter) (int, error)
+	DeleteDataByID(ctx context.Context, id int) error
+	RestoreDataByID(ctx context.Context, id int) error
+	PurgeDeleted(ctx context.Context, olderThan time.Duration) (int64, error)
+	UpdateDataByID(ctx context.Context, id int, newData UsersData, version int) (int, error)
+	PartialUpdateDataByID(ctx context.Context, id int, partialData map[string]interface{}, version int) (int, error)
+	History(ctx context.Context, id int) ([]HistoryEntry, error)
+	// Changes возвращает не более limit записей истории, следующих за записью afterID в порядке
+	// фиксации транзакций. Записи незавершённых транзакций не возвращаются.
+	Changes(ctx context.Context, afterID int64, limit int) ([]HistoryEntry, error)
+	// LastChangeID возвращает идентификатор последней записи истории изменений.
+	LastChangeID(ctx context.Context) (int64, error)
+	// ListenChanges вызывает notify при появлении новых записей истории изменений до отмены ctx.
+	ListenChanges(ctx context.Context, notify func()) error
+	// RelayOutbox передаёт в publish не более limit неопубликованных событий в порядке их записи
+	// и удаляет их, если publish завершился без ошибки. Возвращает количество опубликованных событий.
+	RelayOutbox(ctx context.Context, limit int, publish func([]OutboxEvent) error) (int, error)
+
+	// Подписки webhooks и доставка им событий изменения записей.
+	CreateWebhook(ctx context.Context, w Webhook) (Webhook, error)
+	ListWebhooks(ctx context.Context) ([]Webhook, error)
+	GetWebhook(ctx context.Context, id int64) (Webhook, error)
+	UpdateWebhook(ctx context.Context, w Webhook) (Webhook, error)
+	DeleteWebhook(ctx context.Context, id int64) error
+	WebhookDeliveries(ctx context.Context, webhookID int64, status string, limit int) ([]WebhookDelivery, error)
+	GetWebhookDelivery(ctx context.Context, webhookID, id int64) (WebhookDelivery, error)
+	// ClaimWebhookDeliveries выбирает доставки для отправки и откладывает их повторный выбор на lease.
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]WebhookTask, error)
+	FinishWebhookAttempt(ctx context.Context, deliveryID int64, attempt WebhookAttempt, status string, nextAttempt time.Time) error
+	// ReplayWebhookDeliveries возвращает в очередь неудавшиеся доставки, при deliveryID > 0 — только одну.
+	ReplayWebhookDeliveries(ctx context.Context, webhookID, deliveryID int64) (int64, error)
+
+	// Ключи доступа к API. Передаётся и хранится только хеш ключа.
+	CreateAPIKey(ctx context.Context, name, role, prefix, hash string) (APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int64) error
+	APIKeyByHash(ctx context.Context, hash string) (APIKey, error)
+
+	// Настройки арендатора из контекста.
+	EnrichmentSettings(ctx context.Context) (EnrichmentSettings, error)
+	SetEnrichmentSettings(ctx context.Context, e EnrichmentSettings) (EnrichmentSettings, error)
+
+	// Пакетные операции. При atomic все элементы выполняются в одной транзакции и первая
+	// ошибка откатывает пакет (*BatchError), иначе результат каждого элемента возвращается отдельно.
+	SaveDataBatch(ctx context.Context, items []Data, atomic bool) ([]BatchResult, error)
+	PartialUpdateBatch(ctx context.Context, items []PatchItem, atomic bool) ([]BatchResult, error)
+	DeleteBatch(ctx context.Context, ids []int, atomic bool) ([]BatchResult, error)
+
+	// Задачи импорта файлов в топик Kafka.
+	CreateImportJob(ctx context.Context, id, format string) error
+	UpdateImportJob(ctx context.Context, id string, totalRows, published int) error
+	FinishImportJob(ctx context.Context, id, status, errMsg string) error
+	AddImportRejection(ctx context.Context, jobID string, rej ImportRejection) error
+	GetImportJob(ctx context.Context, id string) (ImportJob, error)
 }
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"regexp"
+	"time"
+)
+
+// DefaultTenant арендатор запросов и сообщений, в которых арендатор не указан.
+const DefaultTenant = "default"
+
+// AllTenants значение арендатора для фоновых задач, обрабатывающих данные всех арендаторов
+// (окончательное удаление, поток изменений, outbox, доставка webhooks). Запросы, ограниченные
+// арендатором, с этим значением не находят записей.
+const AllTenants = "*"
+
+// tenantRegex допустимый идентификатор арендатора.
+var tenantRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// ValidTenant проверяет идентификатор арендатора, полученный от клиента.
+func ValidTenant(tenant string) bool {
+	return tenantRegex.MatchString(tenant)
+}
+
+type tenantKey struct{}
+
+// WithTenant возвращает контекст с арендатором, данные которого обрабатываются.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFrom возвращает арендатора из контекста или DefaultTenant.
+func TenantFrom(ctx context.Context) string {
+	if t, ok := ctx.Value(tenantKey{}).(string); ok && t != "" {
+		return t
+	}
+	return DefaultTenant
+}
+
+// EnrichmentSettings Настройки обогащения записей арендатора.
+type EnrichmentSettings struct {
+	Age         bool      `json:"age"`         // определять возраст (agify.io)
+	Gender      bool      `json:"gender"`      // определять пол (genderize.io)
+	Nationality bool      `json:"nationality"` // определять национальность (nationalize.io)
+	CountryID   string    `json:"country_id"`  // код страны ISO 3166-1 для уточнения возраста и пола
+	APIKey      string    `json:"api_key,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// DefaultEnrichment настройки обогащения арендатора, для которого они не заданы.
+var DefaultEnrichment = EnrichmentSettings{Age: true, Gender: true, Nationality: true}
@@ -0,0 +1,56 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"syscall"
+)
+
+// ErrForbiddenAddress адрес подписчика относится к локальной или внутренней сети.
+var ErrForbiddenAddress = errors.New("адрес подписчика относится к локальной или внутренней сети")
+
+// sharedAddressSpace адреса операторов связи (RFC 6598), не входящие в net.IP.IsPrivate.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// forbiddenIP сообщает, относится ли адрес к локальной, внутренней или служебной сети:
+// loopback, RFC 1918 и fc00::/7, link-local (в том числе 169.254.169.254), multicast.
+func forbiddenIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip)
+}
+
+// CheckURL проверяет, что все адреса узла подписчика находятся вне локальных и внутренних сетей.
+func CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	host := u.Hostname()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("не удалось определить адрес %s", host)
+	}
+	for _, addr := range addrs {
+		if forbiddenIP(addr.IP) {
+			return fmt.Errorf("%s: %w", host, ErrForbiddenAddress)
+		}
+	}
+	return nil
+}
+
+// dialControl запрещает соединения с адресами локальных и внутренних сетей. Проверяется адрес
+// после разрешения имени, поэтому ограничение действует и при перенаправлениях, и при смене
+// записей DNS после регистрации подписки.
+func dialControl(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || forbiddenIP(ip) {
+		return fmt.Errorf("%s: %w", host, ErrForbiddenAddress)
+	}
+	return nil
+}
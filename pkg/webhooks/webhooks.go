@@ -0,0 +1,196 @@
+// Package webhooks доставка событий изменения записей подписчикам по HTTP.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/zatrasz75/Service/configs"
+	"github.com/zatrasz75/Service/pkg/logger"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Значения по умолчанию.
+const (
+	defaultTimeout      = 10 * time.Second
+	defaultMaxAttempts  = 8
+	defaultBackoff      = 30 * time.Second
+	defaultPollInterval = time.Second
+	defaultConcurrency  = 4
+	maxBackoff          = 6 * time.Hour
+)
+
+// Заголовки запроса доставки.
+const (
+	HeaderID        = "X-Webhook-Id"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Dispatcher Отправляет подписчикам доставки из очереди webhook_deliveries.
+type Dispatcher struct {
+	db          storage.Database
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	interval    time.Duration
+	concurrency int
+}
+
+// New создаёт Dispatcher. Нулевые значения настроек заменяются значениями по умолчанию.
+// Без cfg.AllowPrivate запросы к адресам локальных и внутренних сетей запрещены.
+func New(cfg configs.Webhooks, db storage.Database) *Dispatcher {
+	d := &Dispatcher{
+		db:          db,
+		client:      &http.Client{Timeout: cfg.Timeout},
+		maxAttempts: cfg.MaxAttempts,
+		backoff:     cfg.Backoff,
+		interval:    cfg.PollInterval,
+		concurrency: cfg.Concurrency,
+	}
+	if d.client.Timeout <= 0 {
+		d.client.Timeout = defaultTimeout
+	}
+	if d.maxAttempts <= 0 {
+		d.maxAttempts = defaultMaxAttempts
+	}
+	if d.backoff <= 0 {
+		d.backoff = defaultBackoff
+	}
+	if d.interval <= 0 {
+		d.interval = defaultPollInterval
+	}
+	if d.concurrency <= 0 {
+		d.concurrency = defaultConcurrency
+	}
+	if !cfg.AllowPrivate {
+		// Прокси не используется, иначе проверялся бы адрес прокси, а не подписчика.
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = nil
+		transport.DialContext = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: dialControl}).DialContext
+		d.client.Transport = transport
+	}
+	return d
+}
+
+// Sign возвращает значение заголовка X-Webhook-Signature: время отправки и HMAC-SHA256
+// строки "<timestamp>.<body>" с секретом подписки.
+func Sign(secret string, timestamp int64, body []byte) string {
+	ts := strconv.FormatInt(timestamp, 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run отправляет доставки до отмены ctx. Несколько реплик могут работать одновременно:
+// каждая доставка выбирается только одной из них.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	// Доставка не повторяется, пока не истечёт время ожидания ответа.
+	lease := d.client.Timeout + time.Minute
+
+	for {
+		tasks, err := d.db.ClaimWebhookDeliveries(ctx, d.concurrency, lease)
+		if err != nil && ctx.Err() == nil {
+			logger.Error("не удалось выбрать доставки webhooks", err)
+		}
+
+		var wg sync.WaitGroup
+		for _, t := range tasks {
+			wg.Add(1)
+			go func(t storage.WebhookTask) {
+				defer wg.Done()
+				d.deliver(ctx, t)
+			}(t)
+		}
+		wg.Wait()
+
+		// Полный пакет означает, что в очереди могут остаться доставки.
+		if err == nil && len(tasks) == d.concurrency {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliver отправляет событие подписчику и записывает результат попытки.
+func (d *Dispatcher) deliver(ctx context.Context, t storage.WebhookTask) {
+	start := time.Now()
+	code, err := d.send(ctx, t)
+
+	attempt := storage.WebhookAttempt{
+		AttemptedAt: start,
+		StatusCode:  code,
+		DurationMS:  int(time.Since(start).Milliseconds()),
+	}
+	status := storage.DeliverySucceeded
+	next := time.Now()
+	if err != nil {
+		attempt.Error = err.Error()
+		status = storage.DeliveryPending
+		next = next.Add(d.delay(t.Attempts + 1))
+		if t.Attempts+1 >= d.maxAttempts {
+			status = storage.DeliveryFailed
+		}
+	}
+
+	if err = d.db.FinishWebhookAttempt(ctx, t.ID, attempt, status, next); err != nil {
+		logger.Error("не удалось записать результат доставки webhook", err)
+	}
+}
+
+// send выполняет запрос доставки. Успешным считается ответ с кодом 2xx.
+func (d *Dispatcher) send(ctx context.Context, t storage.WebhookTask) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, bytes.NewReader(t.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderID, strconv.FormatInt(t.ID, 10))
+	req.Header.Set(HeaderEvent, t.EventType)
+	req.Header.Set(HeaderSignature, Sign(t.Secret, time.Now().Unix(), t.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("подписчик ответил %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// delay возвращает задержку перед следующей попыткой: экспоненциальный рост от backoff
+// с разбросом ±20%, не более maxBackoff.
+func (d *Dispatcher) delay(attempts int) time.Duration {
+	delay := d.backoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxBackoff)
+
+	jitter := time.Duration(rand.Int63n(int64(delay)/5*2+1)) - delay/5
+	return delay + jitter
+}
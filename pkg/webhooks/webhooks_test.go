@@ -0,0 +1,192 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"github.com/zatrasz75/Service/configs"
+	"github.com/zatrasz75/Service/pkg/storage"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestForbiddenIP(t *testing.T) {
+	tests := []struct {
+		ip        string
+		forbidden bool
+	}{
+		{"127.0.0.1", true},
+		{"127.1.2.3", true},
+		{"::1", true},
+		{"10.0.0.1", true},
+		{"172.16.0.1", true},
+		{"172.31.255.255", true},
+		{"192.168.1.1", true},
+		{"fc00::1", true},
+		{"fd12:3456::1", true},
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+		{"100.64.0.1", true},
+		{"100.127.255.255", true},
+		{"224.0.0.1", true},
+		{"ff02::1", true},
+		{"0.0.0.0", true},
+		{"::", true},
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:10.0.0.1", true},
+		{"::ffff:169.254.169.254", true},
+		{"::ffff:192.168.0.1", true},
+
+		{"93.184.216.34", false},
+		{"8.8.8.8", false},
+		{"172.32.0.1", false},
+		{"100.128.0.1", false},
+		{"2606:4700::1111", false},
+		{"::ffff:8.8.8.8", false},
+	}
+	for _, tt := range tests {
+		ip := net.ParseIP(tt.ip)
+		if ip == nil {
+			t.Fatalf("некорректный адрес %s", tt.ip)
+		}
+		if got := forbiddenIP(ip); got != tt.forbidden {
+			t.Errorf("%s: запрещён %v, ожидалось %v", tt.ip, got, tt.forbidden)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url       string
+		forbidden bool
+	}{
+		{"https://127.0.0.1/hook", true},
+		{"http://[::1]:8080/hook", true},
+		{"http://[::ffff:7f00:1]/hook", true},
+		{"http://169.254.169.254/latest/meta-data/", true},
+		{"http://10.1.2.3:9000/", true},
+		{"http://localhost/hook", true},
+		{"https://93.184.216.34/hook", false},
+		{"https://[2606:4700::1111]/hook", false},
+	}
+	for _, tt := range tests {
+		err := CheckURL(context.Background(), tt.url)
+		if got := errors.Is(err, ErrForbiddenAddress); got != tt.forbidden {
+			t.Errorf("%s: ошибка %v, ожидался запрет %v", tt.url, err, tt.forbidden)
+		}
+		if !tt.forbidden && err != nil {
+			t.Errorf("%s: %v", tt.url, err)
+		}
+	}
+}
+
+func TestDialControl(t *testing.T) {
+	tests := []struct {
+		address   string
+		forbidden bool
+	}{
+		{"127.0.0.1:80", true},
+		{"[::1]:443", true},
+		{"[::ffff:192.168.0.1]:80", true},
+		{"169.254.169.254:80", true},
+		{"93.184.216.34:443", false},
+		{"[2606:4700::1111]:443", false},
+	}
+	for _, tt := range tests {
+		err := dialControl("tcp", tt.address, nil)
+		if got := errors.Is(err, ErrForbiddenAddress); got != tt.forbidden || (!tt.forbidden && err != nil) {
+			t.Errorf("%s: ошибка %v, ожидался запрет %v", tt.address, err, tt.forbidden)
+		}
+	}
+	if err := dialControl("tcp", "93.184.216.34", nil); err == nil {
+		t.Error("адрес без порта принят")
+	}
+}
+
+// TestSendPrivateAddress проверяет, что Dispatcher не соединяется с локальными адресами
+// без настройки AllowPrivate.
+func TestSendPrivateAddress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	task := storage.WebhookTask{URL: srv.URL, Secret: "secret"}
+
+	if _, err := New(configs.Webhooks{}, nil).send(context.Background(), task); !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("ошибка %v, ожидалась %v", err, ErrForbiddenAddress)
+	}
+	if code, err := New(configs.Webhooks{AllowPrivate: true}, nil).send(context.Background(), task); err != nil || code != http.StatusOK {
+		t.Errorf("с AllowPrivate: код %d, ошибка %v", code, err)
+	}
+}
+
+func TestSign(t *testing.T) {
+	got := Sign("secret", 1700000000, []byte(`{"id":1}`))
+	want := "t=1700000000,v1=3dd1b9aef568d75f6790a84bd2e5dfa1f44409eef3cbdbd3f10b837376100c11"
+	if got != want {
+		t.Errorf("получено %s, ожидалось %s", got, want)
+	}
+	if Sign("other", 1700000000, []byte(`{"id":1}`)) == got {
+		t.Error("подпись не зависит от секрета")
+	}
+	if Sign("secret", 1700000001, []byte(`{"id":1}`))[len("t=1700000001,"):] == got[len("t=1700000000,"):] {
+		t.Error("подпись не зависит от времени")
+	}
+}
+
+// TestSendHeaders проверяет заголовки запроса доставки и подпись тела.
+func TestSendHeaders(t *testing.T) {
+	signature := regexp.MustCompile(`^t=(\d+),v1=[0-9a-f]{64}$`)
+	payload := []byte(`{"type":"person.created"}`)
+
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	task := storage.WebhookTask{URL: srv.URL, Secret: "secret"}
+	task.ID, task.EventType, task.Payload = 7, storage.EventPersonCreated, payload
+	code, err := New(configs.Webhooks{AllowPrivate: true}, nil).send(context.Background(), task)
+	if code != http.StatusInternalServerError || err == nil {
+		t.Fatalf("код %d, ошибка %v: ответ 5xx должен быть ошибкой", code, err)
+	}
+
+	if got.Get(HeaderID) != "7" || got.Get(HeaderEvent) != storage.EventPersonCreated {
+		t.Errorf("заголовки %s=%q, %s=%q", HeaderID, got.Get(HeaderID), HeaderEvent, got.Get(HeaderEvent))
+	}
+	m := signature.FindStringSubmatch(got.Get(HeaderSignature))
+	if m == nil {
+		t.Fatalf("некорректный формат подписи %q", got.Get(HeaderSignature))
+	}
+	ts, _ := strconv.ParseInt(m[1], 10, 64)
+	if got.Get(HeaderSignature) != Sign("secret", ts, payload) {
+		t.Errorf("подпись %q не совпадает с подписью тела", got.Get(HeaderSignature))
+	}
+}
+
+func TestDelay(t *testing.T) {
+	d := New(configs.Webhooks{Backoff: time.Minute}, nil)
+	tests := []struct {
+		attempts int
+		base     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{6, 32 * time.Minute},
+		{10, maxBackoff},
+		{100, maxBackoff},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			got := d.delay(tt.attempts)
+			if got < tt.base-tt.base/5 || got > tt.base+tt.base/5 {
+				t.Fatalf("попытка %d: задержка %v вне %v ±20%%", tt.attempts, got, tt.base)
+			}
+		}
+	}
+}